@@ -1,11 +1,15 @@
 package helpers
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"fmt"
 	"log"
 
 	protocol "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1"
 	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+	"github.com/MTRNord/matrix_protobuf_fed/rpcserver"
 )
 
 func QuoteString(s string) string {
@@ -13,13 +17,17 @@ func QuoteString(s string) string {
 }
 
 type KeyStreamCallback struct {
+	// The server name announced in the metadata chunk
+	serverName string
+	// The keys announced in the verify_keys chunk
+	verifyKeys map[rpcserver.KeyID]ed25519.PublicKey
 }
 
-func NewKeyStreamCallback() KeyStreamCallback {
-	return KeyStreamCallback{}
+func NewKeyStreamCallback() *KeyStreamCallback {
+	return &KeyStreamCallback{}
 }
 
-func (k KeyStreamCallback) Write(ctx context.Context, call protocol.StreamCallback_write) error {
+func (k *KeyStreamCallback) Write(ctx context.Context, call protocol.StreamCallback_write) error {
 	log.Println("StreamCallback.Write")
 	p := call.Args()
 
@@ -42,6 +50,7 @@ func (k KeyStreamCallback) Write(ctx context.Context, call protocol.StreamCallba
 		valid_until_ts := metadata.ValidUntilTS()
 
 		log.Println("Server keys metadata:\n", "servername:", QuoteString(servername), "valid_until_ts:", valid_until_ts)
+		k.serverName = servername
 	} else if response.HasVerifyKeys() {
 		verify_keys, err := response.VerifyKeys()
 		if err != nil {
@@ -53,6 +62,7 @@ func (k KeyStreamCallback) Write(ctx context.Context, call protocol.StreamCallba
 			return err
 		}
 		len_of_entries := entries.Len()
+		k.verifyKeys = make(map[rpcserver.KeyID]ed25519.PublicKey)
 
 		// Print the verify keys
 		for i := 0; i < len_of_entries; i++ {
@@ -71,9 +81,17 @@ func (k KeyStreamCallback) Write(ctx context.Context, call protocol.StreamCallba
 			}
 
 			key := key_ptr.Data()
+			// The message is released after the call so we have to copy the key
+			k.verifyKeys[rpcserver.KeyID(key_id)] = ed25519.PublicKey(bytes.Clone(key))
 
 			log.Println("Server keys verify_keys entry:", "key_id:", QuoteString(key_id), "key:", key)
 		}
+
+		// The verify keys are self-signed so we can check them right away
+		err = k.verifySelfSigned("verify_keys", verify_keys, response)
+		if err != nil {
+			return err
+		}
 	} else if response.HasOldVerifyKeys() {
 		old_verify_keys, err := response.OldVerifyKeys()
 		if err != nil {
@@ -112,6 +130,12 @@ func (k KeyStreamCallback) Write(ctx context.Context, call protocol.StreamCallba
 
 			log.Println("Server keys old_verify_keys entry:", "key_id:", QuoteString(key_id), "key:", key, "expired_ts:", expired_ts)
 		}
+
+		// The old verify keys are signed with the verify keys we received before
+		err = k.verifySelfSigned("old_verify_keys", old_verify_keys, response)
+		if err != nil {
+			return err
+		}
 	} else {
 		log.Println("Server keys response has no metadata, verify_keys, or old_verify_keys")
 	}
@@ -168,7 +192,34 @@ func (k KeyStreamCallback) Write(ctx context.Context, call protocol.StreamCallba
 	return nil
 }
 
-func (k KeyStreamCallback) Done(ctx context.Context, call protocol.StreamCallback_done) error {
+func (k *KeyStreamCallback) verifySelfSigned(name string, signed types.Map, response types.ServerKeysResponse) error {
+	if !response.HasSignatures() {
+		return fmt.Errorf("server keys %s are not signed", name)
+	}
+	signatures, err := response.Signatures()
+	if err != nil {
+		return err
+	}
+
+	result, err := rpcserver.VerifyCapnprotoMap(signed, signatures, []string{k.serverName}, func(serverName string, keyID rpcserver.KeyID) (ed25519.PublicKey, error) {
+		key, ok := k.verifyKeys[keyID]
+		if !ok {
+			return nil, rpcserver.ErrKeyNotFound
+		}
+		return key, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := result.Err(); err != nil {
+		return fmt.Errorf("server keys %s signature check failed: %w", name, err)
+	}
+	log.Println("Server keys", name, "signature check passed")
+	return nil
+}
+
+func (k *KeyStreamCallback) Done(ctx context.Context, call protocol.StreamCallback_done) error {
 	log.Println("StreamCallback.Done")
 	return nil
 }
@@ -5,81 +5,82 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log"
-	"log/slog"
 	"net"
 	"os"
 	"os/signal"
 	"runtime/pprof"
 	"syscall"
+	"time"
 
 	"capnproto.org/go/capnp/v3"
 	"capnproto.org/go/capnp/v3/flowcontrol"
-	"capnproto.org/go/capnp/v3/rpc"
+	"github.com/MTRNord/matrix_protobuf_fed/eventstore"
+	"github.com/MTRNord/matrix_protobuf_fed/keystore"
 	"github.com/MTRNord/matrix_protobuf_fed/rpcserver"
+	"github.com/MTRNord/matrix_protobuf_fed/sender"
 
 	protocol "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
 )
 
 // This implements a dummy server for testing purposes of the rough api design especially around signatures
 
-// Serve serves a Cap'n Proto RPC to incoming connections.
+// ListenAndServe opens a listener on the given address and serves a Cap'n Proto RPC to incoming connections
 //
-// Serve will take ownership of bootstrapClient and release it after the listener closes.
+// network and address are passed to net.Listen. Use network "unix" for Unix Domain Sockets
+// and "tcp" for regular TCP IP4 or IP6 connections.
 //
-// Serve exits with the listener error if the listener is closed by the owner.
-func Serve(lis net.Listener, boot capnp.Client) error {
-	if !boot.IsValid() {
-		err := errors.New("bootstrap client is not valid")
+// Connections are handled by the given manager. ListenAndServe returns once the manager was shut down.
+func ListenAndServe(network, addr string, manager *rpcserver.ConnManager) error {
+	listener, err := net.Listen(network, addr)
+	if err != nil {
 		return err
 	}
-	// Since we took ownership of the bootstrap client, release it after we're done.
-	defer boot.Release()
-	for {
-		// Accept incoming connections
-		conn, err := lis.Accept()
-		if err != nil {
-			conn.Close()
-			return err
-		}
-		defer conn.Close()
 
-		// the RPC connection takes ownership of the bootstrap interface and will release it when the connection
-		// exits, so use AddRef to avoid releasing the provided bootstrap client capability.
-		opts := rpc.Options{
-			BootstrapClient: boot.AddRef(),
-			Logger:          slog.Default(),
-		}
-		// For each new incoming connection, create a new RPC transport connection that will serve incoming RPC requests
-		transport := rpc.NewStreamTransport(conn)
-		defer transport.Close()
-		rpc_conn := rpc.NewConn(transport, &opts)
-		defer rpc_conn.Close()
+	return manager.Serve(listener)
+}
+
+// resolveServer returns the address of the RPC endpoint of a server. Without an explicit port the port this demo
+// server listens on is used.
+func resolveServer(ctx context.Context, serverName string) (string, error) {
+	if _, _, err := net.SplitHostPort(serverName); err == nil {
+		return serverName, nil
 	}
+	return net.JoinHostPort(serverName, "8449"), nil
 }
 
-// ListenAndServe opens a listener on the given address and serves a Cap'n Proto RPC to incoming connections
-//
-// network and address are passed to net.Listen. Use network "unix" for Unix Domain Sockets
-// and "tcp" for regular TCP IP4 or IP6 connections.
-//
-// ListenAndServe will take ownership of bootstrapClient and release it on exit.
-func ListenAndServe(ctx context.Context, network, addr string, bootstrapClient capnp.Client) error {
-	listener, err := net.Listen(network, addr)
+// wakeSender retries the queue of a server right away when it sends us a transaction, as it is reachable again.
+// The PDUs and EDUs are dropped, this demo server has no homeserver to pass them to.
+type wakeSender struct {
+	sender *sender.Sender
+}
 
-	if err == nil {
-		// to close this listener, close the context
-		go func() {
-			<-ctx.Done()
-			_ = listener.Close()
-		}()
-		err = Serve(listener, bootstrapClient)
-	}
-	return err
+func (w wakeSender) HandlePDU(ctx context.Context, txn rpcserver.TransactionInfo, pdu types.Transaction_PDU, info rpcserver.PDUInfo) error {
+	return nil
+}
+
+func (w wakeSender) HandleEDU(ctx context.Context, txn rpcserver.TransactionInfo, edu types.Transaction_EDU) error {
+	return nil
+}
+
+func (w wakeSender) TransactionDone(ctx context.Context, txn rpcserver.TransactionInfo) error {
+	w.sender.Wake(txn.Origin)
+	return nil
 }
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
 var memprofile = flag.String("memprofile", "", "write memory profile to this file")
+var serverName = flag.String("servername", "localhost", "name of the homeserver this proxy acts for")
+var signingKeyPath = flag.String("signingkey", "signing.key", "path to the Synapse-format signing key file")
+var keyStorePath = flag.String("keystore", "keystore.json", "file to persist the fetched keys of other servers in")
+var oldKeysPath = flag.String("oldkeys", "", "path to a file with retired keys as \"<key id> <base64 public key> <expired_ts>\" per line, keys retired by a rotation are saved to it")
+var replayWindow = flag.Duration("replaywindow", rpcserver.DefaultReplayWindow, "how far the timestamp of a signed request may be away from our time")
+var replayCachePath = flag.String("replaycache", "replay.json", "file to persist the nonces of recent signed requests in")
+var transactionLogPath = flag.String("txnlog", "transactions.log", "file to log completed inbound transactions in to answer retries")
+var eventStorePath = flag.String("eventstore", "events", "directory to store the PDUs served via backfill in")
+var queueDir = flag.String("queuedir", "queues", "directory to persist the queues of events to send to other servers in")
 var f *os.File
 
 func main() {
@@ -93,12 +94,96 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	log.Println("Starting server on port localhost:8449")
+	signingKeys, err := rpcserver.LoadSigningKeys(*serverName, *signingKeyPath)
+	if err != nil {
+		log.Fatalln("Failed to load signing keys:", err)
+	}
+	server, err := rpcserver.NewServer(signingKeys)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *oldKeysPath != "" {
+		// The file is created on the first rotation
+		oldKeys, err := rpcserver.LoadOldVerifyKeys(*oldKeysPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Fatalln("Failed to load old verify keys:", err)
+		}
+		for keyID, oldKey := range oldKeys {
+			err = server.KeyRing().AddOldVerifyKey(keyID, oldKey)
+			if err != nil {
+				log.Fatalln("Failed to load old verify keys:", err)
+			}
+		}
+		server.KeyRing().SetOldVerifyKeysPath(*oldKeysPath)
+	}
+
+	keyStore, err := keystore.Open(*keyStorePath)
+	if err != nil {
+		log.Fatalln("Failed to open key store:", err)
+	}
+	server.SetKeyProvider(keyStore)
+	server.SetVerifyKeySource(keyStore)
+	server.SetKeyFetcher(rpcserver.NewRemoteKeyFetcher(keyStore, sender.NetDialer{Resolve: resolveServer}))
+	err = server.SetReplayProtection(*replayWindow, rpcserver.DefaultReplayCacheSize, *replayCachePath)
+	if err != nil {
+		log.Fatalln("Failed to load replay cache:", err)
+	}
+	err = server.SetTransactionLog(rpcserver.DefaultTransactionLogSize, *transactionLogPath)
+	if err != nil {
+		log.Fatalln("Failed to open transaction log:", err)
+	}
+	eventStore, err := eventstore.Open(*eventStorePath)
+	if err != nil {
+		log.Fatalln("Failed to open event store:", err)
+	}
+	server.SetEventStore(eventStore)
+	eventSender, err := sender.New(server.KeyRing(), sender.NetDialer{Resolve: resolveServer}, *queueDir)
+	if err != nil {
+		log.Fatalln("Failed to load send queues:", err)
+	}
+	server.SetTransactionHandler(wakeSender{sender: eventSender})
+
+	// Reload the signing keys on SIGHUP. Keys removed from the file are published as old verify keys from then on.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			signingKeys, err := rpcserver.LoadSigningKeys(*serverName, *signingKeyPath)
+			if err != nil {
+				log.Println("Failed to reload signing keys:", err)
+				continue
+			}
+			err = server.KeyRing().Rotate(signingKeys)
+			if err != nil {
+				log.Println("Failed to rotate signing keys:", err)
+				continue
+			}
+			log.Println("Reloaded signing keys, now signing with", server.KeyRing().SigningKey().KeyID())
+		}
+	}()
+
+	client := protocol.MatrixFederation_ServerToClient(server)
+	client.SetFlowLimiter(flowcontrol.NewFixedLimiter(1 << 17))
+
+	// The manager takes ownership of the client and drains the server streams on shutdown.
+	manager := rpcserver.NewConnManager(capnp.Client(client), server)
+
 	c := make(chan os.Signal, 2)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM) // subscribe to system signals
 	onKill := func(c chan os.Signal) {
 		select {
 		case <-c:
 			fmt.Println("Got killed")
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := manager.Shutdown(ctx); err != nil {
+				log.Println("Failed to shut down gracefully:", err)
+			}
+			cancel()
+			eventSender.Close()
+			if err := eventStore.Close(); err != nil {
+				log.Println("Failed to close event store:", err)
+			}
 			pprof.StopCPUProfile()
 			f.Close()
 			if *memprofile != "" {
@@ -116,11 +201,10 @@ func main() {
 	// try to handle os interrupt(signal terminated)
 	go onKill(c)
 
-	log.Println("Starting server on port localhost:8449")
-	server := rpcserver.NewServer()
-
-	client := protocol.MatrixFederation_ServerToClient(server)
-	client.SetFlowLimiter(flowcontrol.NewFixedLimiter(1 << 17))
-
-	ListenAndServe(context.Background(), "tcp", "localhost:8449", capnp.Client(client))
+	err = ListenAndServe("tcp", "localhost:8449", manager)
+	if err != nil && !errors.Is(err, rpcserver.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	// Wait for the signal handler to finish the shutdown
+	select {}
 }
@@ -0,0 +1,167 @@
+package eventstore
+
+import (
+	"cmp"
+	"slices"
+)
+
+// depthEntry is an event in the depth index of a room
+type depthEntry struct {
+	depth   uint64
+	eventID string
+}
+
+func compareDepthEntries(a, b depthEntry) int {
+	return cmp.Or(cmp.Compare(a.depth, b.depth), cmp.Compare(a.eventID, b.eventID))
+}
+
+// room is the index of the events of a room
+type room struct {
+	// Events of the room ordered by depth and event ID
+	by_depth []depthEntry
+}
+
+// index adds the event to all indexes. The caller has to hold the write lock.
+func (s *Store) index(eventID string, e *event) {
+	s.events[eventID] = e
+
+	r, ok := s.rooms[e.roomID]
+	if !ok {
+		r = &room{}
+		s.rooms[e.roomID] = r
+	}
+	entry := depthEntry{depth: e.depth, eventID: eventID}
+	i, _ := slices.BinarySearchFunc(r.by_depth, entry, compareDepthEntries)
+	r.by_depth = slices.Insert(r.by_depth, i, entry)
+
+	for _, prev_event := range e.prev_events {
+		s.children[prev_event] = append(s.children[prev_event], eventID)
+	}
+}
+
+// PrevEvents returns the prev events of a stored event. They do not have to be stored themselves.
+func (s *Store) PrevEvents(eventID string) ([]string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.events[eventID]
+	if !ok {
+		return nil, false
+	}
+	return slices.Clone(e.prev_events), true
+}
+
+// AuthEvents returns the auth events of a stored event. They do not have to be stored themselves.
+func (s *Store) AuthEvents(eventID string) ([]string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.events[eventID]
+	if !ok {
+		return nil, false
+	}
+	return slices.Clone(e.auth_events), true
+}
+
+// NextEvents returns the stored events which reference the event as prev event
+func (s *Store) NextEvents(eventID string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.children[eventID])
+}
+
+// RoomEvents returns the stored events of the room with a depth in [minDepth, maxDepth], ordered by depth.
+func (s *Store) RoomEvents(roomID string, minDepth, maxDepth uint64) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.rooms[roomID]
+	if !ok {
+		return nil
+	}
+
+	start, _ := slices.BinarySearchFunc(r.by_depth, depthEntry{depth: minDepth}, compareDepthEntries)
+	var event_ids []string
+	for _, entry := range r.by_depth[start:] {
+		if entry.depth > maxDepth {
+			break
+		}
+		event_ids = append(event_ids, entry.eventID)
+	}
+	return event_ids
+}
+
+// ForwardExtremities returns the stored events of the room no other stored event references as prev event
+func (s *Store) ForwardExtremities(roomID string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.rooms[roomID]
+	if !ok {
+		return nil
+	}
+
+	var event_ids []string
+	for _, entry := range r.by_depth {
+		if len(s.children[entry.eventID]) == 0 {
+			event_ids = append(event_ids, entry.eventID)
+		}
+	}
+	return event_ids
+}
+
+// Backward walks the DAG from the events towards older events via the prev events and returns up to limit stored
+// events in breadth-first order. The starting events are included. Events that are not stored are skipped.
+func (s *Store) Backward(from []string, limit int) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.walk(from, limit, func(eventID string) []string {
+		return s.events[eventID].prev_events
+	})
+}
+
+// Forward walks the DAG from the events towards newer events and returns up to limit stored events in breadth-first
+// order. The starting events are included. Events that are not stored are skipped.
+func (s *Store) Forward(from []string, limit int) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.walk(from, limit, func(eventID string) []string {
+		return s.children[eventID]
+	})
+}
+
+// AuthChain returns the stored events of the auth chain of the events, i.e. their auth events and recursively the
+// auth events of those. The events themselves are not included.
+func (s *Store) AuthChain(eventIDs []string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var start []string
+	for _, event_id := range eventIDs {
+		if e, ok := s.events[event_id]; ok {
+			start = append(start, e.auth_events...)
+		}
+	}
+	return s.walk(start, -1, func(eventID string) []string {
+		return s.events[eventID].auth_events
+	})
+}
+
+// walk does a breadth-first search over the stored events. A negative limit means no limit.
+// The caller has to hold the read lock.
+func (s *Store) walk(from []string, limit int, edges func(eventID string) []string) []string {
+	var event_ids []string
+	seen := make(map[string]struct{})
+	queue := slices.Clone(from)
+	for len(queue) > 0 && (limit < 0 || len(event_ids) < limit) {
+		event_id := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[event_id]; ok {
+			continue
+		}
+		seen[event_id] = struct{}{}
+		if _, ok := s.events[event_id]; !ok {
+			continue
+		}
+
+		event_ids = append(event_ids, event_id)
+		queue = append(queue, edges(event_id)...)
+	}
+	return event_ids
+}
@@ -0,0 +1,35 @@
+package eventstore
+
+import (
+	"github.com/MTRNord/matrix_protobuf_fed/pdu"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// pduEdges are the fields of a PDU the store indexes
+type pduEdges struct {
+	roomID      string
+	depth       uint64
+	prev_events []string
+	auth_events []string
+}
+
+// readEdges reads the indexed fields of a PDU of any room version
+func readEdges(event types.Transaction_PDU) (pduEdges, error) {
+	view, err := pdu.View(event)
+	if err != nil {
+		return pduEdges{}, err
+	}
+	room_id, err := view.RoomID()
+	if err != nil {
+		return pduEdges{}, err
+	}
+	prev_events, err := view.PrevEvents()
+	if err != nil {
+		return pduEdges{}, err
+	}
+	auth_events, err := view.AuthEvents()
+	if err != nil {
+		return pduEdges{}, err
+	}
+	return pduEdges{roomID: room_id, depth: view.Depth(), prev_events: prev_events, auth_events: auth_events}, nil
+}
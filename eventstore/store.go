@@ -0,0 +1,277 @@
+// Package eventstore is an embedded on-disk store for PDUs.
+//
+// The PDUs are appended in their capnproto binary form to a single data file. The index by event ID, room, depth and
+// prev/auth edges is kept in memory and rebuilt from the data file when the store is opened, so there is no external
+// database needed to serve the history of rooms after a restart.
+package eventstore
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	capnp "capnproto.org/go/capnp/v3"
+
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+	"github.com/MTRNord/matrix_protobuf_fed/rpcserver"
+)
+
+// Name of the data file in the directory of the store
+const dataFileName = "events.db"
+
+// Size of the header of a record: the length of the payload and its CRC32
+const recordHeaderSize = 8
+
+// Records larger than this are treated as corruption when reading the data file
+const maxRecordSize = 1 << 26
+
+// ErrStoreClosed is returned when using the store after Close was called.
+var ErrStoreClosed = errors.New("eventstore: closed")
+
+// ErrCorrupted is returned when a stored event can not be read back.
+var ErrCorrupted = errors.New("eventstore: corrupted record")
+
+// An event is the index entry of a stored PDU
+type event struct {
+	pduEdges
+	// Position of the record in the data file
+	offset int64
+	length int
+}
+
+// Store is an EventStore backed by a data file.
+type Store struct {
+	mu     sync.RWMutex
+	file   *os.File
+	size   int64
+	events map[string]*event
+	rooms  map[string]*room
+	// Events referencing an event as prev event, by the referenced event ID.
+	// Edges are kept for events we do not have yet so they are known as soon as the event arrives.
+	children map[string][]string
+}
+
+var _ rpcserver.EventStore = (*Store)(nil)
+
+// Open opens the store in dir. The directory and the data file are created if they do not exist yet.
+//
+// A record at the end of the data file that was not completely written, e.g. after a crash, is dropped. Any other
+// damaged record fails Open, as dropping it would lose the valid records after it.
+func Open(dir string) (*Store, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, dataFileName)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		file:     file,
+		events:   make(map[string]*event),
+		rooms:    make(map[string]*room),
+		children: make(map[string][]string),
+	}
+	err = s.load()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// load rebuilds the index from the data file
+func (s *Store) load() error {
+	reader := bufio.NewReader(s.file)
+	offset := int64(0)
+	for {
+		event_id, data, n, err := readRecord(reader)
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			// The file ends inside the record, so it is the last one and its write did not finish
+			log.Println("Dropping the incomplete record at the end of the event store at offset", offset)
+			err = s.file.Truncate(offset)
+			if err != nil {
+				return err
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("record at offset %d: %w", offset, err)
+		}
+
+		pdu, err := unmarshalPDU(data)
+		if err != nil {
+			return fmt.Errorf("event %s: %w", event_id, err)
+		}
+		edges, err := readEdges(pdu)
+		if err != nil {
+			return fmt.Errorf("event %s: %w", event_id, err)
+		}
+		s.index(event_id, &event{pduEdges: edges, offset: offset + int64(n-len(data)), length: len(data)})
+		offset += int64(n)
+	}
+
+	s.size = offset
+	return nil
+}
+
+// readRecord reads the next record and returns its event ID, the serialized PDU and the size of the whole record.
+// It returns io.EOF if there are no more records, io.ErrUnexpectedEOF if the file ends inside the record and another
+// error if the record is damaged.
+func readRecord(reader *bufio.Reader) (string, []byte, int, error) {
+	var header [recordHeaderSize]byte
+	_, err := io.ReadFull(reader, header[:])
+	if err == io.EOF {
+		return "", nil, 0, io.EOF
+	}
+	if err != nil {
+		return "", nil, 0, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > maxRecordSize {
+		return "", nil, 0, fmt.Errorf("%w: record of %d bytes", ErrCorrupted, length)
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(reader, payload)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return "", nil, 0, fmt.Errorf("%w: checksum mismatch", ErrCorrupted)
+	}
+
+	id_length, n := binary.Uvarint(payload)
+	if n <= 0 || id_length > uint64(len(payload)-n) {
+		return "", nil, 0, fmt.Errorf("%w: invalid event ID", ErrCorrupted)
+	}
+	event_id := string(payload[n : n+int(id_length)])
+	data := payload[n+int(id_length):]
+	return event_id, data, recordHeaderSize + len(payload), nil
+}
+
+// Put stores the PDU under the event ID. Storing an event ID a second time is a no-op.
+// The record is synced to disk before Put returns, so a stored event is not lost in a crash.
+//
+// The event ID is not derived from the PDU, the caller is responsible for passing the right one, e.g. from
+// pdu.EventID.
+func (s *Store) Put(eventID string, pdu types.Transaction_PDU) error {
+	if eventID == "" {
+		return errors.New("eventstore: empty event ID")
+	}
+	edges, err := readEdges(pdu)
+	if err != nil {
+		return err
+	}
+	data, err := marshalPDU(pdu)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+	if _, ok := s.events[eventID]; ok {
+		return nil
+	}
+
+	payload := binary.AppendUvarint(make([]byte, recordHeaderSize, recordHeaderSize+binary.MaxVarintLen64+len(eventID)+len(data)), uint64(len(eventID)))
+	payload = append(payload, eventID...)
+	data_offset := len(payload)
+	payload = append(payload, data...)
+	binary.BigEndian.PutUint32(payload[0:4], uint32(len(payload)-recordHeaderSize))
+	binary.BigEndian.PutUint32(payload[4:8], crc32.ChecksumIEEE(payload[recordHeaderSize:]))
+
+	_, err = s.file.WriteAt(payload, s.size)
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		// Drop whatever made it to the file so the next record starts at a clean offset
+		s.file.Truncate(s.size)
+		return err
+	}
+	s.index(eventID, &event{pduEdges: edges, offset: s.size + int64(data_offset), length: len(data)})
+	s.size += int64(len(payload))
+	return nil
+}
+
+// GetEvent returns the PDU with the event ID if it was stored for the room.
+// The returned PDU has a message of its own and stays valid.
+func (s *Store) GetEvent(ctx context.Context, roomID string, eventID string) (types.Transaction_PDU, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.file == nil {
+		return types.Transaction_PDU{}, ErrStoreClosed
+	}
+	e, ok := s.events[eventID]
+	if !ok || e.roomID != roomID {
+		return types.Transaction_PDU{}, rpcserver.ErrEventNotFound
+	}
+
+	data := make([]byte, e.length)
+	_, err := s.file.ReadAt(data, e.offset)
+	if err != nil {
+		return types.Transaction_PDU{}, err
+	}
+	return unmarshalPDU(data)
+}
+
+// Has reports whether the event is stored
+func (s *Store) Has(eventID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.events[eventID]
+	return ok
+}
+
+// Close flushes the data file to disk and closes it.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Sync()
+	close_err := s.file.Close()
+	s.file = nil
+	return errors.Join(err, close_err)
+}
+
+func marshalPDU(pdu types.Transaction_PDU) ([]byte, error) {
+	msg, _, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return nil, err
+	}
+	err = msg.SetRoot(pdu.ToPtr())
+	if err != nil {
+		return nil, err
+	}
+	return msg.Marshal()
+}
+
+func unmarshalPDU(data []byte) (types.Transaction_PDU, error) {
+	msg, err := capnp.Unmarshal(data)
+	if err != nil {
+		return types.Transaction_PDU{}, fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	root, err := msg.Root()
+	if err != nil {
+		return types.Transaction_PDU{}, fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	return types.Transaction_PDU(root.Struct()), nil
+}
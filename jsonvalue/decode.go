@@ -0,0 +1,256 @@
+package jsonvalue
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	capnp "capnproto.org/go/capnp/v3"
+
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// ErrSyntax is wrapped by errors returned for malformed JSON
+var ErrSyntax = errors.New("jsonvalue: invalid JSON")
+
+// Size of the structs of JsonValue and JsonValue.Field, see the generated NewJsonValue and NewJsonValue_Field
+var (
+	valueSize = capnp.ObjectSize{DataSize: 16, PointerCount: 1}
+	fieldSize = capnp.ObjectSize{DataSize: 0, PointerCount: 2}
+)
+
+// Capacity of the lists arrays and objects are decoded into before they grow
+const initialListCapacity = 8
+
+// Unmarshal decodes the JSON text in data to dst. Trailing data after the value is an error.
+func Unmarshal(data []byte, dst types.JsonValue) error {
+	return Options{}.Unmarshal(data, dst)
+}
+
+// Unmarshal decodes the JSON text in data to dst. Trailing data after the value is an error.
+func (o Options) Unmarshal(data []byte, dst types.JsonValue) error {
+	decoder := o.NewDecoder(bytes.NewReader(data))
+	err := decoder.Decode(dst)
+	if err == io.EOF {
+		return fmt.Errorf("%w: no value", ErrSyntax)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = decoder.decoder.Token()
+	if err != io.EOF {
+		return fmt.Errorf("%w: data after the value", ErrSyntax)
+	}
+	return nil
+}
+
+// A Decoder reads JSON values from a stream.
+//
+// The values are written to the message of dst while they are read, so large values are never held in memory as
+// text or Go values in addition to their capnproto form. As the length of arrays and objects is not known upfront,
+// they are read into lists that grow by doubling. Only the list structs are copied when growing, so the unused space
+// this leaves in the message is a small multiple of the list structs, not of the strings and values they refer to.
+type Decoder struct {
+	decoder *json.Decoder
+	options Options
+}
+
+// NewDecoder creates a Decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	return Options{}.NewDecoder(r)
+}
+
+// NewDecoder creates a Decoder reading from r that converts numbers according to the options
+func (o Options) NewDecoder(r io.Reader) *Decoder {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return &Decoder{decoder: decoder, options: o}
+}
+
+// Decode reads the next JSON value from the stream and writes it to dst. It returns io.EOF at the end of the stream.
+func (d *Decoder) Decode(dst types.JsonValue) error {
+	token, err := d.token()
+	if err != nil {
+		return err
+	}
+	return d.value(token, dst)
+}
+
+// token returns the next token. The end of the stream in the middle of a value is a syntax error.
+func (d *Decoder) token() (json.Token, error) {
+	token, err := d.decoder.Token()
+	var syntax_err *json.SyntaxError
+	if errors.As(err, &syntax_err) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("%w: %w", ErrSyntax, err)
+	}
+	return token, err
+}
+
+func (d *Decoder) value(token json.Token, dst types.JsonValue) error {
+	switch token := token.(type) {
+	case nil:
+		dst.SetNull()
+	case bool:
+		dst.SetBoolean(token)
+	case string:
+		return dst.SetString_(token)
+	case json.Number:
+		return d.options.setJSONNumber(dst, token)
+	case json.Delim:
+		switch token {
+		case '[':
+			return d.array(dst)
+		case '{':
+			return d.object(dst)
+		}
+		return fmt.Errorf("%w: unexpected %v", ErrSyntax, token)
+	default:
+		return fmt.Errorf("%w: unexpected token %v", ErrSyntax, token)
+	}
+	return nil
+}
+
+func (d *Decoder) array(dst types.JsonValue) error {
+	list, err := types.NewJsonValue_List(dst.Segment(), initialListCapacity)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for d.decoder.More() {
+		if n == list.Len() {
+			list, err = resizeValues(list, n, 2*n)
+			if err != nil {
+				return err
+			}
+		}
+		token, err := d.token()
+		if err != nil {
+			return err
+		}
+		err = d.value(token, list.At(n))
+		if err != nil {
+			return fmt.Errorf("%d: %w", n, err)
+		}
+		n++
+	}
+	// The closing bracket
+	_, err = d.token()
+	if err != nil {
+		return err
+	}
+
+	list, err = resizeValues(list, n, n)
+	if err != nil {
+		return err
+	}
+	return dst.SetArray(list)
+}
+
+func (d *Decoder) object(dst types.JsonValue) error {
+	list, err := types.NewJsonValue_Field_List(dst.Segment(), initialListCapacity)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for d.decoder.More() {
+		if n == list.Len() {
+			list, err = resizeFields(list, n, 2*n)
+			if err != nil {
+				return err
+			}
+		}
+		token, err := d.token()
+		if err != nil {
+			return err
+		}
+		name, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("%w: object key %v", ErrSyntax, token)
+		}
+		err = list.At(n).SetName(name)
+		if err != nil {
+			return err
+		}
+		value, err := list.At(n).NewValue()
+		if err != nil {
+			return err
+		}
+		token, err = d.token()
+		if err != nil {
+			return err
+		}
+		err = d.value(token, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		n++
+	}
+	// The closing brace
+	_, err = d.token()
+	if err != nil {
+		return err
+	}
+
+	list, err = resizeFields(list, n, n)
+	if err != nil {
+		return err
+	}
+	return dst.SetObject(list)
+}
+
+// resizeValues moves the first n values of list to a new list of the given size
+func resizeValues(list types.JsonValue_List, n int, size int) (types.JsonValue_List, error) {
+	if size == list.Len() {
+		return list, nil
+	}
+	resized, err := types.NewJsonValue_List(list.Segment(), int32(size))
+	if err != nil {
+		return list, err
+	}
+	for i := 0; i < n; i++ {
+		err = moveStruct(capnp.Struct(resized.At(i)), capnp.Struct(list.At(i)), valueSize)
+		if err != nil {
+			return list, err
+		}
+	}
+	return resized, nil
+}
+
+// resizeFields moves the first n fields of list to a new list of the given size
+func resizeFields(list types.JsonValue_Field_List, n int, size int) (types.JsonValue_Field_List, error) {
+	if size == list.Len() {
+		return list, nil
+	}
+	resized, err := types.NewJsonValue_Field_List(list.Segment(), int32(size))
+	if err != nil {
+		return list, err
+	}
+	for i := 0; i < n; i++ {
+		err = moveStruct(capnp.Struct(resized.At(i)), capnp.Struct(list.At(i)), fieldSize)
+		if err != nil {
+			return list, err
+		}
+	}
+	return resized, nil
+}
+
+// moveStruct makes dst refer to the same data as src. Both have to be in the same message, then setting a pointer
+// only points to the existing data instead of copying it.
+func moveStruct(dst capnp.Struct, src capnp.Struct, size capnp.ObjectSize) error {
+	for offset := capnp.DataOffset(0); offset < capnp.DataOffset(size.DataSize); offset += 8 {
+		dst.SetUint64(offset, src.Uint64(offset))
+	}
+	for i := uint16(0); i < size.PointerCount; i++ {
+		ptr, err := src.Ptr(i)
+		if err != nil {
+			return err
+		}
+		err = dst.SetPtr(i, ptr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package jsonvalue
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// Marshal returns the JSON text of the value. Objects keep the order of their members.
+func Marshal(value types.JsonValue) ([]byte, error) {
+	var buf bytes.Buffer
+	err := Encode(&buf, value)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encode writes the JSON text of the value to w while walking it, without building the whole text in memory first.
+// Objects keep the order of their members.
+func Encode(w io.Writer, value types.JsonValue) error {
+	e := encoder{w: bufio.NewWriter(w)}
+	err := e.value(value)
+	if err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+type encoder struct {
+	w *bufio.Writer
+	// Reused to format numbers and strings
+	buf []byte
+}
+
+// write writes the formatted bytes and keeps the buffer for the next value
+func (e *encoder) write(formatted []byte) error {
+	e.buf = formatted[:0]
+	_, err := e.w.Write(formatted)
+	return err
+}
+
+func (e *encoder) value(value types.JsonValue) error {
+	w := e.w
+	switch value.Which() {
+	case types.JsonValue_Which_null:
+		_, err := w.WriteString("null")
+		return err
+	case types.JsonValue_Which_boolean:
+		_, err := w.WriteString(strconv.FormatBool(value.Boolean()))
+		return err
+	case types.JsonValue_Which_integer:
+		return e.write(strconv.AppendInt(e.buf, value.Integer(), 10))
+	case types.JsonValue_Which_number:
+		number, err := appendNumber(e.buf, value.Number())
+		if err != nil {
+			return err
+		}
+		return e.write(number)
+	case types.JsonValue_Which_string_:
+		s, err := value.String_()
+		if err != nil {
+			return err
+		}
+		return e.write(AppendString(e.buf, s))
+	case types.JsonValue_Which_array:
+		array, err := value.Array()
+		if err != nil {
+			return err
+		}
+		err = w.WriteByte('[')
+		if err != nil {
+			return err
+		}
+		for i := 0; i < array.Len(); i++ {
+			if i > 0 {
+				err = w.WriteByte(',')
+				if err != nil {
+					return err
+				}
+			}
+			err = e.value(array.At(i))
+			if err != nil {
+				return fmt.Errorf("%d: %w", i, err)
+			}
+		}
+		return w.WriteByte(']')
+	case types.JsonValue_Which_object:
+		fields, err := value.Object()
+		if err != nil {
+			return err
+		}
+		err = w.WriteByte('{')
+		if err != nil {
+			return err
+		}
+		for i := 0; i < fields.Len(); i++ {
+			if i > 0 {
+				err = w.WriteByte(',')
+				if err != nil {
+					return err
+				}
+			}
+			name, err := fields.At(i).Name()
+			if err != nil {
+				return err
+			}
+			err = e.write(append(AppendString(e.buf, name), ':'))
+			if err != nil {
+				return err
+			}
+			field_value, err := fields.At(i).Value()
+			if err != nil {
+				return err
+			}
+			err = e.value(field_value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		return w.WriteByte('}')
+	case types.JsonValue_Which_call:
+		call, err := value.Call()
+		if err != nil {
+			return err
+		}
+		function, err := call.Function()
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s", ErrCall, function)
+	default:
+		return fmt.Errorf("unknown JSON value %d", value.Which())
+	}
+}
+
+// appendNumber formats the number like encoding/json does
+func appendNumber(buf []byte, number float64) ([]byte, error) {
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, number)
+	}
+
+	format := byte('f')
+	if abs := math.Abs(number); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	buf = strconv.AppendFloat(buf, number, format, -1, 64)
+	if format == 'e' {
+		// Shorten e-09 to e-9
+		n := len(buf)
+		if n >= 4 && buf[n-4] == 'e' && buf[n-3] == '-' && buf[n-2] == '0' {
+			buf[n-2] = buf[n-1]
+			buf = buf[:n-1]
+		}
+	}
+	return buf, nil
+}
@@ -0,0 +1,327 @@
+// Package jsonvalue converts between types.JsonValue and Go values or JSON text.
+//
+// Objects are represented as Object, which keeps the order of its members, and integral numbers are written as
+// integer, so a value survives a round trip through Go or JSON unchanged. Call values have no JSON form and are
+// rejected unless Options.AllowCalls is set for the conversion from and to Go values.
+package jsonvalue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// ErrCall is returned when converting a call value where calls are not allowed.
+var ErrCall = errors.New("jsonvalue: call values are not allowed")
+
+// ErrUnsupported is returned for Go values that have no JSON representation, e.g. NaN.
+var ErrUnsupported = errors.New("jsonvalue: unsupported value")
+
+// Object is a JSON object that keeps the order of its members
+type Object []Member
+
+// Member is a single key of an Object
+type Member struct {
+	Name  string
+	Value any
+}
+
+// Get returns the value of the first member with the given name
+func (o Object) Get(name string) (any, bool) {
+	index := slices.IndexFunc(o, func(m Member) bool {
+		return m.Name == name
+	})
+	if index < 0 {
+		return nil, false
+	}
+	return o[index].Value, true
+}
+
+// MarshalJSON writes the members in their order
+func (o Object) MarshalJSON() ([]byte, error) {
+	buf := []byte{'{'}
+	for i, member := range o {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = AppendString(buf, member.Name)
+		buf = append(buf, ':')
+		value, err := json.Marshal(member.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, value...)
+	}
+	return append(buf, '}'), nil
+}
+
+// Call is the Go form of a call value like `BinData(0, "Zm9vCg==")`
+type Call struct {
+	Function string
+	Params   []any
+}
+
+// MarshalJSON always fails as calls are not JSON
+func (c Call) MarshalJSON() ([]byte, error) {
+	return nil, fmt.Errorf("%w: %s", ErrCall, c.Function)
+}
+
+// Options change how values are converted. The zero value rejects call values.
+type Options struct {
+	// AllowCalls converts call values from and to Call instead of rejecting them
+	AllowCalls bool
+	// Canonical rejects numbers canonical JSON does not allow: numbers with a fractional part and integers outside of
+	// ±MaxSafeInteger. See https://spec.matrix.org/v1.9/appendices/#canonical-json
+	Canonical bool
+}
+
+// ToGo converts a JsonValue to Go values, rejecting call values. See Options.ToGo.
+func ToGo(value types.JsonValue) (any, error) {
+	return Options{}.ToGo(value)
+}
+
+// FromGo writes a Go value to dst, rejecting call values. See Options.FromGo.
+func FromGo(v any, dst types.JsonValue) error {
+	return Options{}.FromGo(v, dst)
+}
+
+// ToGo converts a JsonValue to nil, bool, int64, float64, string, []any, Object or Call.
+// Integers become int64 and other numbers float64. In canonical mode integral numbers also become int64.
+func (o Options) ToGo(value types.JsonValue) (any, error) {
+	switch value.Which() {
+	case types.JsonValue_Which_null:
+		return nil, nil
+	case types.JsonValue_Which_boolean:
+		return value.Boolean(), nil
+	case types.JsonValue_Which_integer:
+		return o.checkInteger(value.Integer())
+	case types.JsonValue_Which_number:
+		return o.numberToGo(value.Number())
+	case types.JsonValue_Which_string_:
+		return value.String_()
+	case types.JsonValue_Which_array:
+		array, err := value.Array()
+		if err != nil {
+			return nil, err
+		}
+		return o.listToGo(array)
+	case types.JsonValue_Which_object:
+		fields, err := value.Object()
+		if err != nil {
+			return nil, err
+		}
+		object := make(Object, fields.Len())
+		for i := range object {
+			object[i].Name, err = fields.At(i).Name()
+			if err != nil {
+				return nil, err
+			}
+			field_value, err := fields.At(i).Value()
+			if err != nil {
+				return nil, err
+			}
+			object[i].Value, err = o.ToGo(field_value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", object[i].Name, err)
+			}
+		}
+		return object, nil
+	case types.JsonValue_Which_call:
+		call, err := value.Call()
+		if err != nil {
+			return nil, err
+		}
+		function, err := call.Function()
+		if err != nil {
+			return nil, err
+		}
+		if !o.AllowCalls {
+			return nil, fmt.Errorf("%w: %s", ErrCall, function)
+		}
+		params, err := call.Params()
+		if err != nil {
+			return nil, err
+		}
+		params_go, err := o.listToGo(params)
+		if err != nil {
+			return nil, err
+		}
+		return Call{Function: function, Params: params_go}, nil
+	default:
+		return nil, fmt.Errorf("unknown JSON value %d", value.Which())
+	}
+}
+
+func (o Options) listToGo(list types.JsonValue_List) ([]any, error) {
+	result := make([]any, list.Len())
+	for i := range result {
+		var err error
+		result[i], err = o.ToGo(list.At(i))
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", i, err)
+		}
+	}
+	return result, nil
+}
+
+// FromGo writes a Go value to dst.
+//
+// Besides the types returned by ToGo it accepts all integer and float types, json.Number, json.RawMessage and
+// map[string]any, whose keys are written sorted as maps have no order. Any other value is converted with
+// encoding/json first. Integral numbers are written as integer.
+func (o Options) FromGo(v any, dst types.JsonValue) error {
+	switch v := v.(type) {
+	case nil:
+		dst.SetNull()
+	case bool:
+		dst.SetBoolean(v)
+	case string:
+		return dst.SetString_(v)
+	case float64:
+		return o.setFloat(dst, v)
+	case float32:
+		return o.setFloat(dst, float64(v))
+	case int:
+		return o.setInteger(dst, int64(v))
+	case int8:
+		return o.setInteger(dst, int64(v))
+	case int16:
+		return o.setInteger(dst, int64(v))
+	case int32:
+		return o.setInteger(dst, int64(v))
+	case int64:
+		return o.setInteger(dst, v)
+	case uint:
+		return o.setUint(dst, uint64(v))
+	case uint8:
+		return o.setInteger(dst, int64(v))
+	case uint16:
+		return o.setInteger(dst, int64(v))
+	case uint32:
+		return o.setInteger(dst, int64(v))
+	case uint64:
+		return o.setUint(dst, v)
+	case json.Number:
+		return o.setJSONNumber(dst, v)
+	case json.RawMessage:
+		return o.Unmarshal(v, dst)
+	case []any:
+		array, err := dst.NewArray(int32(len(v)))
+		if err != nil {
+			return err
+		}
+		return o.listFromGo(v, array)
+	case Object:
+		fields, err := dst.NewObject(int32(len(v)))
+		if err != nil {
+			return err
+		}
+		for i, member := range v {
+			err = o.fieldFromGo(member.Name, member.Value, fields.At(i))
+			if err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		fields, err := dst.NewObject(int32(len(names)))
+		if err != nil {
+			return err
+		}
+		for i, name := range names {
+			err = o.fieldFromGo(name, v[name], fields.At(i))
+			if err != nil {
+				return err
+			}
+		}
+	case Call:
+		if !o.AllowCalls {
+			return fmt.Errorf("%w: %s", ErrCall, v.Function)
+		}
+		call, err := dst.NewCall()
+		if err != nil {
+			return err
+		}
+		err = call.SetFunction(v.Function)
+		if err != nil {
+			return err
+		}
+		params, err := call.NewParams(int32(len(v.Params)))
+		if err != nil {
+			return err
+		}
+		return o.listFromGo(v.Params, params)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrUnsupported, err)
+		}
+		return o.Unmarshal(data, dst)
+	}
+	return nil
+}
+
+func (o Options) listFromGo(values []any, list types.JsonValue_List) error {
+	for i, value := range values {
+		err := o.FromGo(value, list.At(i))
+		if err != nil {
+			return fmt.Errorf("%d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (o Options) fieldFromGo(name string, value any, field types.JsonValue_Field) error {
+	err := field.SetName(name)
+	if err != nil {
+		return err
+	}
+	field_value, err := field.NewValue()
+	if err != nil {
+		return err
+	}
+	err = o.FromGo(value, field_value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// AppendString appends the JSON string literal of s to buf.
+//
+// Only quotes, backslashes and control characters are escaped, everything else is written as UTF-8. This is the form
+// canonical JSON requires, see https://spec.matrix.org/v1.9/appendices/#canonical-json
+func AppendString(buf []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	buf = append(buf, '"')
+	// Invalid UTF-8 is replaced byte by byte with U+FFFD
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			buf = append(buf, '\\', byte(r))
+		case r == '\b':
+			buf = append(buf, '\\', 'b')
+		case r == '\f':
+			buf = append(buf, '\\', 'f')
+		case r == '\n':
+			buf = append(buf, '\\', 'n')
+		case r == '\r':
+			buf = append(buf, '\\', 'r')
+		case r == '\t':
+			buf = append(buf, '\\', 't')
+		case r < 0x20:
+			buf = append(buf, '\\', 'u', '0', '0', hex[r>>4], hex[r&0xf])
+		default:
+			buf = utf8.AppendRune(buf, r)
+		}
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,87 @@
+package jsonvalue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// ErrNotCanonical is returned in canonical mode for numbers canonical JSON does not allow.
+var ErrNotCanonical = errors.New("jsonvalue: number not allowed in canonical JSON")
+
+// MaxSafeInteger is the largest integer canonical JSON allows
+const MaxSafeInteger = 1<<53 - 1
+
+// setInteger writes an integer
+func (o Options) setInteger(dst types.JsonValue, number int64) error {
+	_, err := o.checkInteger(number)
+	if err != nil {
+		return err
+	}
+	dst.SetInteger(number)
+	return nil
+}
+
+// setUint writes an unsigned integer. Integers too large for int64 are written as number.
+func (o Options) setUint(dst types.JsonValue, number uint64) error {
+	if number > math.MaxInt64 {
+		return o.setFloat(dst, float64(number))
+	}
+	return o.setInteger(dst, int64(number))
+}
+
+// setFloat writes a float. Integral values that fit an int64 are written as integer.
+func (o Options) setFloat(dst types.JsonValue, number float64) error {
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return fmt.Errorf("%w: %v", ErrUnsupported, number)
+	}
+	// -2^63 is exactly representable as float64, 2^63 is the first value that no longer fits
+	if number == math.Trunc(number) && number >= math.MinInt64 && number < math.MaxInt64 {
+		return o.setInteger(dst, int64(number))
+	}
+	if o.Canonical {
+		return fmt.Errorf("%w: %v is not an integer", ErrNotCanonical, number)
+	}
+	dst.SetNumber(number)
+	return nil
+}
+
+// setJSONNumber writes a number literal. Integer literals are parsed without going through float64, so they keep
+// every digit.
+func (o Options) setJSONNumber(dst types.JsonValue, number json.Number) error {
+	integer, err := strconv.ParseInt(string(number), 10, 64)
+	if err == nil {
+		return o.setInteger(dst, integer)
+	}
+	float, err := number.Float64()
+	if err != nil {
+		return fmt.Errorf("%w: number %s", ErrUnsupported, number)
+	}
+	return o.setFloat(dst, float)
+}
+
+// checkInteger returns the integer if it is allowed
+func (o Options) checkInteger(number int64) (any, error) {
+	if o.Canonical && (number > MaxSafeInteger || number < -MaxSafeInteger) {
+		return nil, fmt.Errorf("%w: %d is out of range", ErrNotCanonical, number)
+	}
+	return number, nil
+}
+
+// numberToGo converts a number, which was not written as integer. In canonical mode it has to be integral.
+func (o Options) numberToGo(number float64) (any, error) {
+	if !o.Canonical {
+		return number, nil
+	}
+	if number != math.Trunc(number) {
+		return nil, fmt.Errorf("%w: %v is not an integer", ErrNotCanonical, number)
+	}
+	if math.Abs(number) > MaxSafeInteger {
+		return nil, fmt.Errorf("%w: %v is out of range", ErrNotCanonical, number)
+	}
+	return int64(number), nil
+}
@@ -0,0 +1,210 @@
+// Package keystore remembers the keys of other servers.
+//
+// Keys are cached per server name and key ID together with the time until which they may be used.
+// The store is optionally persisted to a local file so the keys survive restarts.
+package keystore
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/MTRNord/matrix_protobuf_fed/rpcserver"
+)
+
+// MaxValidity is the longest time a key is trusted after it was fetched, regardless of the validUntilTS of the server.
+// See https://spec.matrix.org/v1.9/server-server-api/#publishing-keys
+const MaxValidity = time.Hour * 24 * 7
+
+// ErrKeyExpired is returned if a key is known but not valid at the requested time.
+var ErrKeyExpired = errors.New("key is not valid at the requested time")
+
+// A cachedKey is a single key of a server
+type cachedKey struct {
+	Key ed25519.PublicKey `json:"key"`
+	// POSIX timestamp in milliseconds until which the key may be used.
+	// This is the lesser of the validUntilTS of the server and 7 days after the key was fetched.
+	ValidUntilTS int64 `json:"valid_until_ts"`
+	// POSIX timestamp in milliseconds at which the server stopped using the key. 0 if the key is still in use.
+	ExpiredTS int64 `json:"expired_ts,omitempty"`
+}
+
+type serverEntry struct {
+	// The last response of the server as received. This is served to other servers when acting as a notary.
+	Response *rpcserver.ServerKeys `json:"response"`
+	// POSIX timestamp in milliseconds when the response was fetched
+	FetchedTS int64 `json:"fetched_ts"`
+	// All keys we know of this server, including ones no longer part of the last response
+	Keys map[rpcserver.KeyID]cachedKey `json:"keys"`
+}
+
+// KeyStore caches the keys of remote servers.
+// It implements rpcserver.KeyProvider so it can be used to answer notary requests.
+type KeyStore struct {
+	mu      sync.RWMutex
+	path    string
+	servers map[string]*serverEntry
+}
+
+// New creates an in-memory KeyStore
+func New() *KeyStore {
+	return &KeyStore{
+		servers: make(map[string]*serverEntry),
+	}
+}
+
+// Open creates a KeyStore that is persisted at path. Existing keys are loaded from the file if it exists.
+func Open(path string) (*KeyStore, error) {
+	store := New()
+	store.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &store.servers)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if store.servers == nil {
+		store.servers = make(map[string]*serverEntry)
+	}
+	return store, nil
+}
+
+// Store remembers the keys of a server. The keys have to be verified by the caller.
+//
+// Keys of earlier responses are kept, so signatures made with them can still be checked.
+func (s *KeyStore) Store(keys *rpcserver.ServerKeys) error {
+	if keys.ServerName == "" {
+		return errors.New("server keys without server name")
+	}
+
+	fetched_ts := time.Now().UnixMilli()
+	valid_until_ts := min(keys.ValidUntilTS, fetched_ts+MaxValidity.Milliseconds())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.servers[keys.ServerName]
+	if !ok {
+		entry = &serverEntry{Keys: make(map[rpcserver.KeyID]cachedKey)}
+		s.servers[keys.ServerName] = entry
+	}
+	entry.Response = keys
+	entry.FetchedTS = fetched_ts
+
+	for key_id, key := range keys.VerifyKeys {
+		entry.Keys[key_id] = cachedKey{
+			Key:          key,
+			ValidUntilTS: valid_until_ts,
+		}
+	}
+	for key_id, old_key := range keys.OldVerifyKeys {
+		entry.Keys[key_id] = cachedKey{
+			Key:          old_key.Key,
+			ValidUntilTS: old_key.ExpiredTS,
+			ExpiredTS:    old_key.ExpiredTS,
+		}
+	}
+
+	return s.persist()
+}
+
+// ServerKeys returns the last response of the server or rpcserver.ErrKeyNotFound if we never fetched its keys.
+// The response is returned unmodified so the signatures of the server stay valid. Use ValidUntil to check how long
+// we are willing to trust it.
+func (s *KeyStore) ServerKeys(serverName string) (*rpcserver.ServerKeys, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.servers[serverName]
+	if !ok || entry.Response == nil {
+		return nil, rpcserver.ErrKeyNotFound
+	}
+	return entry.Response, nil
+}
+
+// ValidUntil returns the time until which the current keys of the server may be used.
+// The boolean is false if we do not know the server.
+func (s *KeyStore) ValidUntil(serverName string) (int64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.servers[serverName]
+	if !ok || entry.Response == nil {
+		return 0, false
+	}
+	return min(entry.Response.ValidUntilTS, entry.FetchedTS+MaxValidity.Milliseconds()), true
+}
+
+// VerifyKeyAt returns the key of the server if it was valid at the given POSIX timestamp in milliseconds.
+//
+// It returns rpcserver.ErrKeyNotFound if the key is unknown and ErrKeyExpired if it was not valid at that time.
+func (s *KeyStore) VerifyKeyAt(serverName string, keyID rpcserver.KeyID, ts int64) (ed25519.PublicKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.servers[serverName]
+	if !ok {
+		return nil, rpcserver.ErrKeyNotFound
+	}
+	key, ok := entry.Keys[keyID]
+	if !ok {
+		return nil, rpcserver.ErrKeyNotFound
+	}
+
+	if key.ExpiredTS != 0 && ts >= key.ExpiredTS {
+		return nil, fmt.Errorf("%w: %s of %s expired at %d", ErrKeyExpired, keyID, serverName, key.ExpiredTS)
+	}
+	if ts > key.ValidUntilTS {
+		return nil, fmt.Errorf("%w: %s of %s is valid until %d", ErrKeyExpired, keyID, serverName, key.ValidUntilTS)
+	}
+	return key.Key, nil
+}
+
+// KeyLookupAt returns a rpcserver.KeyLookup which only returns keys that were valid at the given timestamp.
+func (s *KeyStore) KeyLookupAt(ts int64) rpcserver.KeyLookup {
+	return func(serverName string, keyID rpcserver.KeyID) (ed25519.PublicKey, error) {
+		return s.VerifyKeyAt(serverName, keyID, ts)
+	}
+}
+
+// persist writes the store to disk. The caller has to hold the lock.
+func (s *KeyStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s.servers)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a half written store behind
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
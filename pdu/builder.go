@@ -0,0 +1,190 @@
+package pdu
+
+import (
+	"fmt"
+
+	capnp "capnproto.org/go/capnp/v3"
+
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// Builder fills the group of a room version of a PDU. It also implements PDU to read back what was set.
+//
+// The fields all room versions share are set with the methods of the generated groups, e.g. SetDepth or NewContent.
+type Builder struct {
+	*view
+}
+
+// Build selects the group of the room version in the union of pdu and returns a Builder for it.
+// The room version is the identifier used in the room, e.g. "1" or "11".
+func Build(pdu types.Transaction_PDU, roomVersion string) (*Builder, error) {
+	switch roomVersion {
+	case "1":
+		pdu.SetRoomVersion1()
+	case "2":
+		pdu.SetRoomVersion2()
+	case "3":
+		pdu.SetRoomVersion3()
+	case "4":
+		pdu.SetRoomVersion4()
+	case "5":
+		pdu.SetRoomVersion5()
+	case "6":
+		pdu.SetRoomVersion6()
+	case "7":
+		pdu.SetRoomVersion7()
+	case "8":
+		pdu.SetRoomVersion8()
+	case "9":
+		pdu.SetRoomVersion9()
+	case "10":
+		pdu.SetRoomVersion10()
+	case "11":
+		pdu.SetRoomVersion11()
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownRoomVersion, roomVersion)
+	}
+
+	v, err := newView(pdu)
+	if err != nil {
+		return nil, err
+	}
+	return &Builder{view: v}, nil
+}
+
+// SetEventID sets the event ID. Only room versions 1 and 2 send it with the PDU.
+func (b *Builder) SetEventID(eventID string) error {
+	if b.event_id == nil {
+		return fmt.Errorf("%w: event ID, room version %s", ErrNotInRoomVersion, b.version)
+	}
+	return b.event_id.SetEventID(eventID)
+}
+
+// SetStateKey sets the state key. The generated setter stores an empty text as null pointer, which reads back as no
+// state key, while the empty state key is the most common one.
+func (b *Builder) SetStateKey(stateKey string) error {
+	return b.setText(stateKeyPointers, "state key", stateKey)
+}
+
+// SetOrigin sets the deprecated top level origin key. Like SetStateKey it keeps an empty origin.
+func (b *Builder) SetOrigin(origin string) error {
+	return b.setText(originPointers, "origin", origin)
+}
+
+// SetMembership sets the deprecated top level membership key. Like SetStateKey it keeps an empty membership.
+func (b *Builder) SetMembership(membership string) error {
+	return b.setText(membershipPointers, "membership", membership)
+}
+
+// setText sets a text field of the group at the pointer index the schema gives it, also if the text is empty
+func (b *Builder) setText(pointers func() (map[uint16]uint16, error), name string, value string) error {
+	indexes, err := pointers()
+	if err != nil {
+		return err
+	}
+	pointer, ok := indexes[uint16(b.pdu.Which())]
+	if !ok {
+		return fmt.Errorf("%w: %s, room version %s", ErrNotInRoomVersion, name, b.version)
+	}
+	return capnp.Struct(b.pdu).SetNewText(pointer, value)
+}
+
+// SetRedacts sets the top level redacts key. From room version 11 it has to be set in the content instead.
+func (b *Builder) SetRedacts(eventID string) error {
+	if b.redacts == nil {
+		return fmt.Errorf("%w: redacts, room version %s", ErrNotInRoomVersion, b.version)
+	}
+	return b.redacts.SetRedacts(eventID)
+}
+
+// SetAuthEvents sets the auth events by ID. In room versions 1 and 2 the references are written without hashes.
+func (b *Builder) SetAuthEvents(eventIDs []string) error {
+	return b.SetAuthEventReferences(idReferences(eventIDs))
+}
+
+// SetPrevEvents sets the prev events by ID. In room versions 1 and 2 the references are written without hashes.
+func (b *Builder) SetPrevEvents(eventIDs []string) error {
+	return b.SetPrevEventReferences(idReferences(eventIDs))
+}
+
+// SetAuthEventReferences sets the auth events. The hashes are only written in room versions 1 and 2.
+func (b *Builder) SetAuthEventReferences(references []EventReference) error {
+	if b.text != nil {
+		list, err := b.text.NewAuthEvents(int32(len(references)))
+		if err != nil {
+			return err
+		}
+		return setTextReferences(list.Set, references)
+	}
+	list, err := b.references.NewAuthEvents(referenceEntries(references))
+	if err != nil {
+		return err
+	}
+	return writeReferences(list, references)
+}
+
+// SetPrevEventReferences sets the prev events. The hashes are only written in room versions 1 and 2.
+func (b *Builder) SetPrevEventReferences(references []EventReference) error {
+	if b.text != nil {
+		list, err := b.text.NewPrevEvents(int32(len(references)))
+		if err != nil {
+			return err
+		}
+		return setTextReferences(list.Set, references)
+	}
+	list, err := b.references.NewPrevEvents(referenceEntries(references))
+	if err != nil {
+		return err
+	}
+	return writeReferences(list, references)
+}
+
+func idReferences(eventIDs []string) []EventReference {
+	references := make([]EventReference, len(eventIDs))
+	for i, event_id := range eventIDs {
+		references[i].EventID = event_id
+	}
+	return references
+}
+
+func setTextReferences(set func(int, string) error, references []EventReference) error {
+	for i, reference := range references {
+		err := set(i, reference.EventID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// referenceEntries returns the number of list entries needed to write the references
+func referenceEntries(references []EventReference) int32 {
+	n := int32(len(references))
+	for _, reference := range references {
+		if reference.SHA256 != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// writeReferences writes an eventID entry per reference, followed by a sha256 entry if the hash is known
+func writeReferences(list types.Transaction_PDU_EventReference_List, references []EventReference) error {
+	i := 0
+	for _, reference := range references {
+		err := list.At(i).SetEventID(reference.EventID)
+		if err != nil {
+			return err
+		}
+		i++
+		if reference.SHA256 == "" {
+			continue
+		}
+		err = list.At(i).SetSha256(reference.SHA256)
+		if err != nil {
+			return err
+		}
+		i++
+	}
+	return nil
+}
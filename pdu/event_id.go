@@ -0,0 +1,52 @@
+package pdu
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// EventID returns the ID of the event.
+//
+// Room versions 1 and 2 send the event ID with the PDU. Later room versions derive it from the reference hash, which
+// is encoded as unpadded standard base64 in room version 3 and as unpadded URL-safe base64 from room version 4 on.
+// See https://spec.matrix.org/v1.9/rooms/v4/#event-ids
+func EventID(p PDU) (string, error) {
+	switch p.RoomVersion() {
+	case "1", "2":
+		return p.EventID()
+	case "3":
+		hash, err := ReferenceHash(p)
+		if err != nil {
+			return "", err
+		}
+		return "$" + base64.RawStdEncoding.EncodeToString(hash), nil
+	default:
+		hash, err := ReferenceHash(p)
+		if err != nil {
+			return "", err
+		}
+		return "$" + base64.RawURLEncoding.EncodeToString(hash), nil
+	}
+}
+
+// ReferenceHash returns the sha256 hash of the canonical JSON of the redacted event without its signatures and
+// unsigned data as described in https://spec.matrix.org/v1.9/server-server-api/#calculating-the-reference-hash-for-an-event
+func ReferenceHash(p PDU) ([]byte, error) {
+	redacted, err := redact(p)
+	if err != nil {
+		return nil, err
+	}
+	event, err := eventJSON(redacted)
+	if err != nil {
+		return nil, err
+	}
+	delete(event, "signatures")
+	delete(event, "unsigned")
+
+	data, err := canonicalJSON(event)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+	return hash[:], nil
+}
@@ -0,0 +1,164 @@
+package pdu_test
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/MTRNord/matrix_protobuf_fed/pdu"
+)
+
+// The event signed in the event signing tests of Synapse (tests/crypto/test_event_signing.py) with the key
+// ed25519:1 of the server domain. Synapse computed its content hash and signature.
+const (
+	synapseEvent = `{"auth_events":[],"content":{},"depth":3,` +
+		`"hashes":{"sha256":"5jM4wQpv6lnBo7CLIghJuHdW+s2CMBJPUOGOC89ncos"},"origin":"domain",` +
+		`"origin_server_ts":1000000,"prev_events":[],"room_id":"!x:domain","sender":"@a:domain",` +
+		`"signatures":{"domain":{"ed25519:1":"` + synapseSignature + `"}},"type":"X","unsigned":{"age_ts":1000000}}`
+	// The redacted event without signatures and unsigned data, the bytes Synapse signed and the reference hash covers
+	synapseSigned = `{"auth_events":[],"content":{},"depth":3,` +
+		`"hashes":{"sha256":"5jM4wQpv6lnBo7CLIghJuHdW+s2CMBJPUOGOC89ncos"},"origin":"domain",` +
+		`"origin_server_ts":1000000,"prev_events":[],"room_id":"!x:domain","sender":"@a:domain","type":"X"}`
+	synapseKey       = "XGX0JRS2Af3be3knz2fBiRbApjm2Dh61gXDJA8kcJNI"
+	synapseSignature = "KxwGjPSDEtvnFgU00fwFz+l6d2pJM6XBIaMEn81SXPTRl16AqLAYqfIReFGZlHi5KLjAWbOoMszkwsQma+lYAg"
+	// sha256 of synapseSigned. Its base64 has no characters that differ between the standard and URL-safe alphabet.
+	synapseEventID = "$8yif6p8EqgoSten2BLje9ntKm720NyFLWQv9tn8memc"
+)
+
+func TestSynapseVector(t *testing.T) {
+	key, err := base64.RawStdEncoding.DecodeString(synapseKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := base64.RawStdEncoding.DecodeString(synapseSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(key, []byte(synapseSigned), signature) {
+		t.Fatal("synapse signature does not verify, the test vector is broken")
+	}
+	hash := sha256.Sum256([]byte(synapseSigned))
+
+	for _, roomVersion := range []string{"3", "4", "5", "6", "7", "8", "9", "10"} {
+		t.Run("room version "+roomVersion, func(t *testing.T) {
+			b := fromJSON(t, synapseEvent, roomVersion)
+			err := pdu.VerifyContentHash(b)
+			if err != nil {
+				t.Errorf("VerifyContentHash: %v", err)
+			}
+			reference_hash, err := pdu.ReferenceHash(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(reference_hash) != string(hash[:]) {
+				t.Error("reference hash is not the hash of the signed bytes")
+			}
+			event_id, err := pdu.EventID(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event_id != synapseEventID {
+				t.Errorf("got %s, want %s", event_id, synapseEventID)
+			}
+		})
+	}
+}
+
+func TestEventIDRoomVersion11DropsOrigin(t *testing.T) {
+	event_id, err := pdu.EventID(fromJSON(t, synapseEvent, "11"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256([]byte(strings.Replace(synapseSigned, `"origin":"domain",`, "", 1)))
+	expected := "$" + base64.RawURLEncoding.EncodeToString(hash[:])
+	if event_id != expected {
+		t.Errorf("got %s, want %s", event_id, expected)
+	}
+}
+
+func TestEventIDEncoding(t *testing.T) {
+	// The depth is chosen so the reference hash contains both + and / in standard base64
+	const signed = `{"auth_events":[],"content":{},"depth":5,` +
+		`"hashes":{"sha256":"5jM4wQpv6lnBo7CLIghJuHdW+s2CMBJPUOGOC89ncos"},"origin":"domain",` +
+		`"origin_server_ts":1000000,"prev_events":[],"room_id":"!x:domain","sender":"@a:domain","type":"X"}`
+	event := strings.TrimSuffix(signed, "}") + `,"signatures":{},"unsigned":{"age":1}}`
+	hash := sha256.Sum256([]byte(signed))
+	standard := "$" + base64.RawStdEncoding.EncodeToString(hash[:])
+	url_safe := "$" + base64.RawURLEncoding.EncodeToString(hash[:])
+	if !strings.ContainsAny(standard, "+/") {
+		t.Fatal("the hash does not tell the encodings apart")
+	}
+
+	for _, test := range []struct {
+		roomVersion string
+		eventID     string
+	}{
+		{"3", standard},
+		{"4", url_safe},
+		{"5", url_safe},
+		{"10", url_safe},
+	} {
+		t.Run("room version "+test.roomVersion, func(t *testing.T) {
+			event_id, err := pdu.EventID(fromJSON(t, event, test.roomVersion))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event_id != test.eventID {
+				t.Errorf("got %s, want %s", event_id, test.eventID)
+			}
+		})
+	}
+}
+
+func TestEventIDCarried(t *testing.T) {
+	// Room versions 1 and 2 reference events with their hashes and send the ID with the event
+	event := strings.Replace(synapseEvent, `"depth":3,`, `"depth":3,"event_id":"$0:domain",`, 1)
+	event = strings.Replace(event, `"prev_events":[]`, `"prev_events":[["$p:domain",{"sha256":"aGFzaA"}]]`, 1)
+	for _, roomVersion := range []string{"1", "2"} {
+		t.Run("room version "+roomVersion, func(t *testing.T) {
+			event_id, err := pdu.EventID(fromJSON(t, event, roomVersion))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event_id != "$0:domain" {
+				t.Errorf("got %s, want $0:domain", event_id)
+			}
+		})
+	}
+}
+
+func TestEventIDRedactedContent(t *testing.T) {
+	// The content of an event of type X does not survive a redaction, so the event has the same ID as the Synapse
+	// event and its signature still verifies
+	event := strings.Replace(synapseEvent, `"content":{}`, `"content":{"body":"Here is the message content"}`, 1)
+	for _, roomVersion := range []string{"3", "10", "11"} {
+		t.Run("room version "+roomVersion, func(t *testing.T) {
+			b := fromJSON(t, event, roomVersion)
+			event_id, err := pdu.EventID(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := pdu.EventID(fromJSON(t, synapseEvent, roomVersion))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event_id != expected {
+				t.Errorf("got %s, want %s", event_id, expected)
+			}
+
+			redacted, err := pdu.Redact(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := pdu.CanonicalJSON(redacted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), "Here is the message content") {
+				t.Errorf("redacted event still has its content: %s", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package pdu
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	capnp "capnproto.org/go/capnp/v3"
+
+	"github.com/MTRNord/matrix_protobuf_fed/jsonvalue"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// ErrNotRepresentable is returned for JSON events that can not be converted to a PDU without losing data, e.g.
+// because they have top level keys the schema has no field for.
+var ErrNotRepresentable = errors.New("pdu: JSON event can not be represented as PDU")
+
+// CanonicalJSON returns the PDU in the JSON event format of the federation API, encoded as canonical JSON.
+// For a PDU created by FromJSON these are the same bytes as the canonical JSON of the original event, so the
+// signatures made over the JSON form of the event can still be verified.
+func CanonicalJSON(p PDU) ([]byte, error) {
+	event, err := eventJSON(p)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalJSON(event)
+}
+
+// FromJSON converts an event in the JSON format of the federation API to a PDU of the room version in dst.
+//
+// Room versions 1 and 2 reference events as `[event_id, {"sha256": hash}]` pairs and carry the event ID, later room
+// versions reference events by ID only. From room version 11 redacts is part of the content and a top level redacts
+// key is rejected. The deprecated top level origin, membership and prev_state are kept in every room version.
+// Unsigned data of only an age is stored as age, any other unsigned object is kept as it was sent.
+//
+// The conversion is lossless: CanonicalJSON of the result is byte for byte the canonical JSON of data. Events that
+// can not be converted that way, e.g. because they have keys the schema has no field for, are rejected with
+// ErrNotRepresentable. Events that have no canonical JSON form, e.g. because they contain fractional numbers, are
+// rejected with ErrNotCanonical.
+func FromJSON(data []byte, roomVersion string, dst types.Transaction_PDU) (*Builder, error) {
+	b, err := Build(dst, roomVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return nil, err
+	}
+	value, err := types.NewRootJsonValue(seg)
+	if err != nil {
+		return nil, err
+	}
+	err = jsonvalue.Options{Canonical: true}.Unmarshal(data, value)
+	if errors.Is(err, jsonvalue.ErrNotCanonical) {
+		return nil, fmt.Errorf("%w: %w", ErrNotCanonical, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value.Which() != types.JsonValue_Which_object {
+		return nil, fmt.Errorf("%w: event is not an object", ErrNotRepresentable)
+	}
+	go_value, err := canonicalGo(value)
+	if err != nil {
+		return nil, err
+	}
+	event, err := objectMap(go_value)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkEventKeys(event, b)
+	if err != nil {
+		return nil, err
+	}
+	err = setEventFields(b, event)
+	if err != nil {
+		return nil, err
+	}
+
+	// Content, prev_state and unsigned are copied as JsonValue to keep them as they were sent.
+	// Setting a pointer from another message copies it.
+	fields, err := sortedFields(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		switch f.name {
+		case "content":
+			err = b.SetContent(f.value)
+		case "prev_state":
+			err = b.SetPrevState(f.value)
+		case "unsigned":
+			err = setUnsigned(b, event["unsigned"], f.value)
+			if err != nil {
+				err = fmt.Errorf("unsigned: %w", err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Everything that could change the canonical JSON was rejected above, this only guards against mistakes in the
+	// conversion
+	expected, err := canonicalJSON(event)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := CanonicalJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(expected, actual) {
+		return nil, fmt.Errorf("%w: event changed during the conversion", ErrNotRepresentable)
+	}
+	return b, nil
+}
+
+// checkEventKeys checks that the event has every key the PDU always has and no key it can not have
+func checkEventKeys(event map[string]any, b *Builder) error {
+	required := []string{
+		"auth_events", "content", "depth", "hashes", "origin_server_ts", "prev_events", "room_id", "sender",
+		"signatures", "type",
+	}
+	optional := []string{"state_key", "unsigned", "origin", "membership", "prev_state"}
+	if b.event_id != nil {
+		required = append(required, "event_id")
+	}
+	if b.redacts != nil {
+		optional = append(optional, "redacts")
+	}
+
+	for _, key := range required {
+		if _, ok := event[key]; !ok {
+			return fmt.Errorf("%w: missing %s", ErrNotRepresentable, key)
+		}
+	}
+	keys := make([]string, 0, len(event))
+	for key := range event {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if !slices.Contains(required, key) && !slices.Contains(optional, key) {
+			return fmt.Errorf("%w: key %s in room version %s", ErrNotRepresentable, key, b.version)
+		}
+	}
+	return nil
+}
+
+// setEventFields sets all fields of the PDU except content, prev_state and unsigned
+func setEventFields(b *Builder, event map[string]any) error {
+	depth, err := integerKey(event, "depth")
+	if err != nil {
+		return err
+	}
+	if depth < 0 {
+		return fmt.Errorf("%w: negative depth", ErrNotRepresentable)
+	}
+	b.SetDepth(uint64(depth))
+	origin_server_ts, err := integerKey(event, "origin_server_ts")
+	if err != nil {
+		return err
+	}
+	b.SetOriginServerTS(origin_server_ts)
+
+	for key, set := range map[string]func(string) error{
+		"room_id":    b.SetRoomID,
+		"sender":     b.SetSender,
+		"type":       b.SetType,
+		"state_key":  b.SetStateKey,
+		"redacts":    b.SetRedacts,
+		"event_id":   b.SetEventID,
+		"origin":     b.SetOrigin,
+		"membership": b.SetMembership,
+	} {
+		if _, ok := event[key]; !ok {
+			continue
+		}
+		value, err := stringKey(event, key)
+		if err != nil {
+			return err
+		}
+		err = set(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	auth_events, err := referencesFromJSON(b.version, event["auth_events"])
+	if err != nil {
+		return fmt.Errorf("auth_events: %w", err)
+	}
+	err = b.SetAuthEventReferences(auth_events)
+	if err != nil {
+		return err
+	}
+	prev_events, err := referencesFromJSON(b.version, event["prev_events"])
+	if err != nil {
+		return fmt.Errorf("prev_events: %w", err)
+	}
+	err = b.SetPrevEventReferences(prev_events)
+	if err != nil {
+		return err
+	}
+
+	err = setHashes(b, event["hashes"])
+	if err != nil {
+		return fmt.Errorf("hashes: %w", err)
+	}
+	err = setSignatures(b, event["signatures"])
+	if err != nil {
+		return fmt.Errorf("signatures: %w", err)
+	}
+	return nil
+}
+
+func stringKey(event map[string]any, key string) (string, error) {
+	value, ok := event[key].(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s is not a string", ErrNotRepresentable, key)
+	}
+	return value, nil
+}
+
+func integerKey(event map[string]any, key string) (int64, error) {
+	value, ok := event[key].(int64)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s is not an integer", ErrNotRepresentable, key)
+	}
+	return value, nil
+}
+
+// referencesFromJSON reads `[event_id, {"sha256": hash}]` pairs in room versions 1 and 2 and event IDs otherwise.
+// It is the inverse of referencesJSON.
+func referencesFromJSON(roomVersion string, value any) ([]EventReference, error) {
+	list, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an array", ErrNotRepresentable)
+	}
+	references := make([]EventReference, len(list))
+	for i, element := range list {
+		if roomVersion != "1" && roomVersion != "2" {
+			event_id, ok := element.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %d is not an event ID", ErrNotRepresentable, i)
+			}
+			references[i].EventID = event_id
+			continue
+		}
+
+		pair, ok := element.([]any)
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("%w: %d is not an event ID and hashes pair", ErrNotRepresentable, i)
+		}
+		event_id, ok := pair[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %d is not an event ID and hashes pair", ErrNotRepresentable, i)
+		}
+		hashes, err := objectMap(pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", i, err)
+		}
+		references[i].EventID = event_id
+		for algorithm, hash := range hashes {
+			hash, ok := hash.(string)
+			// An empty hash can not be told apart from a missing one
+			if algorithm != "sha256" || !ok || hash == "" {
+				return nil, fmt.Errorf("%w: %d has a hash other than a sha256 string", ErrNotRepresentable, i)
+			}
+			references[i].SHA256 = hash
+		}
+	}
+	return references, nil
+}
+
+// setHashes writes the hashes object as `<algorithm>:<hash>` entries. It is the inverse of hashesJSON.
+func setHashes(b *Builder, value any) error {
+	hashes, err := objectMap(value)
+	if err != nil {
+		return err
+	}
+	algorithms := make([]string, 0, len(hashes))
+	for algorithm := range hashes {
+		if strings.Contains(algorithm, ":") {
+			return fmt.Errorf("%w: algorithm %q contains a colon", ErrNotRepresentable, algorithm)
+		}
+		algorithms = append(algorithms, algorithm)
+	}
+	slices.Sort(algorithms)
+
+	list, err := b.NewHashes(int32(len(algorithms)))
+	if err != nil {
+		return err
+	}
+	for i, algorithm := range algorithms {
+		hash, ok := hashes[algorithm].(string)
+		if !ok {
+			return fmt.Errorf("%w: %s is not a string", ErrNotRepresentable, algorithm)
+		}
+		err = list.Set(i, algorithm+":"+hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setSignatures writes `{server: {key_id: base64 signature}}` sorted by server and key ID. It is the inverse of
+// signaturesJSON.
+func setSignatures(b *Builder, value any) error {
+	signatures, err := objectMap(value)
+	if err != nil {
+		return err
+	}
+	servers := make([]string, 0, len(signatures))
+	for server := range signatures {
+		servers = append(servers, server)
+	}
+	slices.Sort(servers)
+
+	list, err := b.NewSignatures(int32(len(servers)))
+	if err != nil {
+		return err
+	}
+	seg := list.Segment()
+	for i, server := range servers {
+		key_signatures, err := objectMap(signatures[server])
+		if err != nil {
+			return fmt.Errorf("%s: %w", server, err)
+		}
+		key_ids := make([]string, 0, len(key_signatures))
+		for key_id := range key_signatures {
+			key_ids = append(key_ids, key_id)
+		}
+		slices.Sort(key_ids)
+
+		signature := list.At(i)
+		err = signature.SetServer(server)
+		if err != nil {
+			return err
+		}
+		signature_map, err := signature.NewSignatures()
+		if err != nil {
+			return err
+		}
+		entries, err := signature_map.NewEntries(int32(len(key_ids)))
+		if err != nil {
+			return err
+		}
+		for j, key_id := range key_ids {
+			encoded, ok := key_signatures[key_id].(string)
+			if !ok {
+				return fmt.Errorf("%w: %s %s is not a string", ErrNotRepresentable, server, key_id)
+			}
+			// Only unpadded base64 is written back the same way
+			decoded, err := base64.RawStdEncoding.Strict().DecodeString(encoded)
+			if err != nil {
+				return fmt.Errorf("%w: %s %s is not unpadded base64", ErrNotRepresentable, server, key_id)
+			}
+
+			key, err := capnp.NewText(seg, key_id)
+			if err != nil {
+				return err
+			}
+			err = entries.At(j).SetKey(key.ToPtr())
+			if err != nil {
+				return err
+			}
+			data, err := capnp.NewData(seg, decoded)
+			if err != nil {
+				return err
+			}
+			err = entries.At(j).SetValue(data.ToPtr())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setUnsigned writes the unsigned data. An object of only a non-negative integer age is stored as age, any other
+// object is kept as raw next to the age it may have.
+func setUnsigned(b *Builder, value any, raw types.JsonValue) error {
+	unsigned, err := objectMap(value)
+	if err != nil {
+		return err
+	}
+	result, err := b.NewUnsigned()
+	if err != nil {
+		return err
+	}
+	age, ok := unsigned["age"].(int64)
+	if ok && age >= 0 {
+		result.SetAge(uint64(age))
+		if len(unsigned) == 1 {
+			return nil
+		}
+	}
+	return result.SetObject(raw)
+}
@@ -0,0 +1,197 @@
+package pdu_test
+
+import (
+	"errors"
+	"testing"
+
+	capnp "capnproto.org/go/capnp/v3"
+
+	"github.com/MTRNord/matrix_protobuf_fed/pdu"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+func newPDU(t *testing.T) types.Transaction_PDU {
+	t.Helper()
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := types.NewRootTransaction_PDU(seg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func fromJSON(t *testing.T, event string, roomVersion string) *pdu.Builder {
+	t.Helper()
+	b, err := pdu.FromJSON([]byte(event), roomVersion, newPDU(t))
+	if err != nil {
+		t.Fatalf("FromJSON: %v", err)
+	}
+	return b
+}
+
+func TestFromJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		roomVersion string
+		event       string
+		// The canonical JSON of event, if it is not already canonical
+		canonical string
+	}{
+		{
+			name:        "room version 1 with references and legacy keys",
+			roomVersion: "1",
+			event: `{"auth_events":[["$a:x",{"sha256":"aGFzaA"}]],"content":{"membership":"join"},"depth":2,` +
+				`"event_id":"$e:x","hashes":{"sha256":"aGFzaA"},"membership":"join","origin":"x",` +
+				`"origin_server_ts":1,"prev_events":[["$p:x",{}]],"prev_state":[],"room_id":"!r:x","sender":"@u:x",` +
+				`"signatures":{"x":{"ed25519:1":"AAAA"}},"state_key":"@u:x","type":"m.room.member",` +
+				`"unsigned":{"age":5,"prev_content":{"membership":"invite"},"replaces_state":"$o:x"}}`,
+		},
+		{
+			name:        "room version 2 with an empty origin and membership",
+			roomVersion: "2",
+			event: `{"auth_events":[],"content":{},"depth":1,"event_id":"$e:x","hashes":{"sha256":"aGFzaA"},` +
+				`"membership":"","origin":"","origin_server_ts":1,"prev_events":[],"room_id":"!r:x","sender":"@u:x",` +
+				`"signatures":{},"type":"m.room.message"}`,
+		},
+		{
+			name:        "room version 3 with unsigned without age",
+			roomVersion: "3",
+			event: `{"auth_events":[],"content":{},"depth":3,"hashes":{"sha256":"aGFzaA"},"origin":"domain",` +
+				`"origin_server_ts":1000000,"prev_events":[],"room_id":"!x:domain","sender":"@a:domain",` +
+				`"signatures":{},"type":"X","unsigned":{"age_ts":1000000}}`,
+		},
+		{
+			name:        "room version 10 with an empty state key and age only",
+			roomVersion: "10",
+			event: `{"auth_events":["$a"],"content":{"membership":"leave"},"depth":4,"hashes":{"sha256":"aGFzaA"},` +
+				`"origin_server_ts":1,"prev_events":["$p"],"redacts":"$r","room_id":"!r:x","sender":"@u:x",` +
+				`"signatures":{"x":{"ed25519:1":"AAAA"}},"state_key":"","type":"m.room.member","unsigned":{"age":0}}`,
+		},
+		{
+			name:        "room version 11 with legacy keys and a negative age",
+			roomVersion: "11",
+			event: `{"auth_events":[],"content":{"redacts":"$r"},"depth":1,"hashes":{"sha256":"aGFzaA"},` +
+				`"origin":"x","origin_server_ts":1,"prev_events":[],"prev_state":[["$s",{"sha256":"aGFzaA"}]],` +
+				`"room_id":"!r:x","sender":"@u:x","signatures":{},"type":"m.room.redaction","unsigned":{"age":-1}}`,
+		},
+		{
+			name:        "keys out of order and whitespace",
+			roomVersion: "4",
+			event: `{ "type": "m.room.message", "sender": "@u:x", "room_id": "!r:x", "depth": 1,
+				"origin_server_ts": 1, "content": {"msgtype": "m.text", "body": "é"}, "auth_events": [],
+				"prev_events": [], "hashes": {"sha256": "aGFzaA"}, "signatures": {}, "origin": "x",
+				"unsigned": {"transaction_id": "t", "age": 1} }`,
+			canonical: `{"auth_events":[],"content":{"body":"é","msgtype":"m.text"},"depth":1,` +
+				`"hashes":{"sha256":"aGFzaA"},"origin":"x","origin_server_ts":1,"prev_events":[],"room_id":"!r:x",` +
+				`"sender":"@u:x","signatures":{},"type":"m.room.message","unsigned":{"age":1,"transaction_id":"t"}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := fromJSON(t, test.event, test.roomVersion)
+			data, err := pdu.CanonicalJSON(b)
+			if err != nil {
+				t.Fatalf("CanonicalJSON: %v", err)
+			}
+			expected := test.canonical
+			if expected == "" {
+				expected = test.event
+			}
+			if string(data) != expected {
+				t.Errorf("got  %s\nwant %s", data, expected)
+			}
+
+			// The converted PDU has to survive being sent
+			msg := capnp.Struct(b.Struct()).Message()
+			encoded, err := msg.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := capnp.Unmarshal(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, err := types.ReadRootTransaction_PDU(decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			view, err := pdu.View(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err = pdu.CanonicalJSON(view)
+			if err != nil {
+				t.Fatalf("CanonicalJSON after unmarshal: %v", err)
+			}
+			if string(data) != expected {
+				t.Errorf("after unmarshal got  %s\nwant %s", data, expected)
+			}
+		})
+	}
+}
+
+func TestFromJSONRejects(t *testing.T) {
+	const keys = `"auth_events":[],"depth":1,"hashes":{},"origin_server_ts":1,"prev_events":[],"room_id":"!r:x",` +
+		`"sender":"@u:x","signatures":{},"type":"t"`
+	tests := []struct {
+		name        string
+		roomVersion string
+		event       string
+		err         error
+	}{
+		{"unknown key", "10", `{` + keys + `,"content":{},"foo":1}`, pdu.ErrNotRepresentable},
+		{"missing content", "10", `{` + keys + `}`, pdu.ErrNotRepresentable},
+		{"top level redacts in room version 11", "11", `{` + keys + `,"content":{},"redacts":"$r"}`,
+			pdu.ErrNotRepresentable},
+		{"missing event ID in room version 1", "1", `{` + keys + `,"content":{}}`, pdu.ErrNotRepresentable},
+		{"origin not a string", "10", `{` + keys + `,"content":{},"origin":1}`, pdu.ErrNotRepresentable},
+		{"unsigned not an object", "10", `{` + keys + `,"content":{},"unsigned":[]}`, pdu.ErrNotRepresentable},
+		{"fraction", "10", `{` + keys + `,"content":{"n":1.5}}`, pdu.ErrNotCanonical},
+		{"fraction in unsigned", "10", `{` + keys + `,"content":{},"unsigned":{"n":0.5}}`, pdu.ErrNotCanonical},
+		{"duplicate key", "10", `{` + keys + `,"content":{"a":1,"a":2}}`, pdu.ErrNotCanonical},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := pdu.FromJSON([]byte(test.event), test.roomVersion, newPDU(t))
+			if !errors.Is(err, test.err) {
+				t.Errorf("got %v, want %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestRedactLegacyKeys(t *testing.T) {
+	const event = `{"auth_events":[],"content":{"membership":"join","displayname":"u"},"depth":1,` +
+		`"hashes":{"sha256":"aGFzaA"},"membership":"join","origin":"x","origin_server_ts":1,"prev_events":[],` +
+		`"prev_state":[],"room_id":"!r:x","sender":"@u:x","signatures":{},"state_key":"@u:x",` +
+		`"type":"m.room.member","unsigned":{"age":1}}`
+	tests := []struct {
+		roomVersion string
+		redacted    string
+	}{
+		{"10", `{"auth_events":[],"content":{"membership":"join"},"depth":1,"hashes":{"sha256":"aGFzaA"},` +
+			`"membership":"join","origin":"x","origin_server_ts":1,"prev_events":[],"prev_state":[],` +
+			`"room_id":"!r:x","sender":"@u:x","signatures":{},"state_key":"@u:x","type":"m.room.member"}`},
+		{"11", `{"auth_events":[],"content":{"membership":"join"},"depth":1,"hashes":{"sha256":"aGFzaA"},` +
+			`"origin_server_ts":1,"prev_events":[],"room_id":"!r:x","sender":"@u:x","signatures":{},` +
+			`"state_key":"@u:x","type":"m.room.member"}`},
+	}
+	for _, test := range tests {
+		t.Run("room version "+test.roomVersion, func(t *testing.T) {
+			redacted, err := pdu.Redact(fromJSON(t, event, test.roomVersion))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := pdu.CanonicalJSON(redacted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.redacted {
+				t.Errorf("got  %s\nwant %s", data, test.redacted)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package pdu
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMissingContentHash is returned if a PDU has no sha256 content hash.
+var ErrMissingContentHash = errors.New("pdu: missing sha256 content hash")
+
+// ErrContentHashMismatch is returned if the content hash of a PDU does not match its content.
+var ErrContentHashMismatch = errors.New("pdu: content hash mismatch")
+
+// ContentHash returns the sha256 hash of the canonical JSON of the event without its unsigned data, signatures and
+// hashes as described in https://spec.matrix.org/v1.9/server-server-api/#calculating-the-content-hash-for-an-event
+func ContentHash(p PDU) ([]byte, error) {
+	event, err := eventJSON(p)
+	if err != nil {
+		return nil, err
+	}
+	delete(event, "unsigned")
+	delete(event, "signatures")
+	delete(event, "hashes")
+
+	data, err := canonicalJSON(event)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+	return hash[:], nil
+}
+
+// SetContentHash sets hashes to the sha256 content hash. It has to be called after all other fields of the event
+// except the signatures were set.
+func (b *Builder) SetContentHash() error {
+	hash, err := ContentHash(b)
+	if err != nil {
+		return err
+	}
+	hashes, err := b.NewHashes(1)
+	if err != nil {
+		return err
+	}
+	return hashes.Set(0, "sha256:"+base64.RawStdEncoding.EncodeToString(hash))
+}
+
+// VerifyContentHash checks that the sha256 content hash of the PDU matches its content.
+//
+// A PDU failing this check has been tampered with. It has to be redacted before being processed further.
+func VerifyContentHash(p PDU) error {
+	hashes, err := textList(p.Hashes())
+	if err != nil {
+		return err
+	}
+	var expected string
+	for _, hash := range hashes {
+		algorithm, value, _ := strings.Cut(hash, ":")
+		if algorithm == "sha256" {
+			expected = value
+			break
+		}
+	}
+	if expected == "" {
+		return ErrMissingContentHash
+	}
+	expected_hash, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(expected, "="))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrContentHashMismatch, err)
+	}
+
+	hash, err := ContentHash(p)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(hash, expected_hash) != 1 {
+		return ErrContentHashMismatch
+	}
+	return nil
+}
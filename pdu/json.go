@@ -0,0 +1,313 @@
+package pdu
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/MTRNord/matrix_protobuf_fed/jsonvalue"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// ErrNotCanonical is returned if a PDU can not be expressed as canonical JSON,
+// e.g. because its content contains fractional numbers or duplicate keys.
+var ErrNotCanonical = errors.New("pdu: no canonical JSON form")
+
+// eventJSON returns the PDU in the JSON event format of the federation API.
+//
+// The event itself is a map[string]any, the content, prev_state and unsigned object are converted with canonicalGo.
+func eventJSON(p PDU) (map[string]any, error) {
+	event := map[string]any{
+		"depth":            int64(p.Depth()),
+		"origin_server_ts": p.OriginServerTS(),
+	}
+
+	for key, get := range map[string]func() (string, error){
+		"room_id": p.RoomID,
+		"sender":  p.Sender,
+		"type":    p.Type,
+	} {
+		value, err := get()
+		if err != nil {
+			return nil, err
+		}
+		event[key] = value
+	}
+	if p.HasStateKey() {
+		state_key, err := p.StateKey()
+		if err != nil {
+			return nil, err
+		}
+		event["state_key"] = state_key
+	}
+	if p.HasRedacts() {
+		redacts, err := p.Redacts()
+		if err != nil {
+			return nil, err
+		}
+		event["redacts"] = redacts
+	}
+	if p.HasOrigin() {
+		origin, err := p.Origin()
+		if err != nil {
+			return nil, err
+		}
+		event["origin"] = origin
+	}
+	if p.HasMembership() {
+		membership, err := p.Membership()
+		if err != nil {
+			return nil, err
+		}
+		event["membership"] = membership
+	}
+	if p.HasPrevState() {
+		value, err := p.PrevState()
+		if err != nil {
+			return nil, err
+		}
+		event["prev_state"], err = canonicalGo(value)
+		if err != nil {
+			return nil, fmt.Errorf("prev_state: %w", err)
+		}
+	}
+	if p.RoomVersion() == "1" || p.RoomVersion() == "2" {
+		event_id, err := p.EventID()
+		if err != nil {
+			return nil, err
+		}
+		event["event_id"] = event_id
+	}
+
+	content := any(map[string]any{})
+	if p.HasContent() {
+		value, err := p.Content()
+		if err != nil {
+			return nil, err
+		}
+		content, err = canonicalGo(value)
+		if err != nil {
+			return nil, fmt.Errorf("content: %w", err)
+		}
+	}
+	event["content"] = content
+
+	auth_events, err := p.AuthEventReferences()
+	if err != nil {
+		return nil, err
+	}
+	event["auth_events"] = referencesJSON(p.RoomVersion(), auth_events)
+	prev_events, err := p.PrevEventReferences()
+	if err != nil {
+		return nil, err
+	}
+	event["prev_events"] = referencesJSON(p.RoomVersion(), prev_events)
+
+	hashes, err := textList(p.Hashes())
+	if err != nil {
+		return nil, err
+	}
+	event["hashes"], err = hashesJSON(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	signatures, err := p.Signatures()
+	if err != nil {
+		return nil, err
+	}
+	event["signatures"], err = signaturesJSON(signatures)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.HasUnsigned() {
+		unsigned, err := p.Unsigned()
+		if err != nil {
+			return nil, err
+		}
+		// The object is only set if the unsigned data is more than the age
+		if unsigned.HasObject() {
+			value, err := unsigned.Object()
+			if err != nil {
+				return nil, err
+			}
+			event["unsigned"], err = canonicalGo(value)
+			if err != nil {
+				return nil, fmt.Errorf("unsigned: %w", err)
+			}
+		} else {
+			event["unsigned"] = map[string]any{"age": int64(unsigned.Age())}
+		}
+	}
+	return event, nil
+}
+
+// referencesJSON returns `[event_id, {"sha256": hash}]` pairs in room versions 1 and 2 and event IDs otherwise
+func referencesJSON(roomVersion string, references []EventReference) []any {
+	result := make([]any, len(references))
+	for i, reference := range references {
+		if roomVersion != "1" && roomVersion != "2" {
+			result[i] = reference.EventID
+			continue
+		}
+		hashes := map[string]any{}
+		if reference.SHA256 != "" {
+			hashes["sha256"] = reference.SHA256
+		}
+		result[i] = []any{reference.EventID, hashes}
+	}
+	return result
+}
+
+// hashesJSON converts `<algorithm>:<hash>` entries to the hashes object
+func hashesJSON(hashes []string) (map[string]any, error) {
+	result := make(map[string]any, len(hashes))
+	for _, hash := range hashes {
+		algorithm, value, ok := strings.Cut(hash, ":")
+		if !ok {
+			return nil, fmt.Errorf("hash %q without algorithm", hash)
+		}
+		if _, ok := result[algorithm]; ok {
+			return nil, fmt.Errorf("%w: duplicate %s hash", ErrNotCanonical, algorithm)
+		}
+		result[algorithm] = value
+	}
+	return result, nil
+}
+
+// signaturesJSON converts the signatures to `{server: {key_id: base64 signature}}`
+func signaturesJSON(signatures types.Signature_List) (map[string]any, error) {
+	result := make(map[string]any, signatures.Len())
+	for i := 0; i < signatures.Len(); i++ {
+		signature := signatures.At(i)
+		server, err := signature.Server()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := result[server]; ok {
+			return nil, fmt.Errorf("%w: duplicate signatures of %s", ErrNotCanonical, server)
+		}
+		key_signatures := map[string]any{}
+		result[server] = key_signatures
+
+		signature_map, err := signature.Signatures()
+		if err != nil {
+			return nil, err
+		}
+		entries, err := signature_map.Entries()
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < entries.Len(); j++ {
+			key, err := entries.At(j).Key()
+			if err != nil {
+				return nil, err
+			}
+			value, err := entries.At(j).Value()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := key_signatures[key.Text()]; ok {
+				return nil, fmt.Errorf("%w: duplicate signature of %s by %s", ErrNotCanonical, server, key.Text())
+			}
+			key_signatures[key.Text()] = base64.RawStdEncoding.EncodeToString(value.Data())
+		}
+	}
+	return result, nil
+}
+
+// canonicalGo converts a JsonValue to Go values with jsonvalue in canonical mode, so numbers are int64 and objects
+// jsonvalue.Object. Values canonical JSON does not allow are rejected with ErrNotCanonical.
+func canonicalGo(value types.JsonValue) (any, error) {
+	result, err := jsonvalue.Options{Canonical: true}.ToGo(value)
+	if errors.Is(err, jsonvalue.ErrNotCanonical) || errors.Is(err, jsonvalue.ErrCall) {
+		return nil, fmt.Errorf("%w: %w", ErrNotCanonical, err)
+	}
+	return result, err
+}
+
+// objectMap returns the members of a jsonvalue.Object by name. Objects with duplicate keys have no canonical form.
+func objectMap(value any) (map[string]any, error) {
+	object, ok := value.(jsonvalue.Object)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an object", ErrNotRepresentable)
+	}
+	result := make(map[string]any, len(object))
+	for _, member := range object {
+		if _, ok := result[member.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate key %q", ErrNotCanonical, member.Name)
+		}
+		result[member.Name] = member.Value
+	}
+	return result, nil
+}
+
+// canonicalJSON encodes the value as described in https://spec.matrix.org/v1.9/appendices/#canonical-json
+func canonicalJSON(value any) ([]byte, error) {
+	return appendCanonical(nil, value)
+}
+
+func appendCanonical(buf []byte, value any) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, "null"...), nil
+	case bool:
+		return strconv.AppendBool(buf, v), nil
+	case int64:
+		if v > jsonvalue.MaxSafeInteger || v < -jsonvalue.MaxSafeInteger {
+			return nil, fmt.Errorf("%w: %d is out of range", ErrNotCanonical, v)
+		}
+		return strconv.AppendInt(buf, v, 10), nil
+	case float64:
+		return nil, fmt.Errorf("%w: %v is not an integer", ErrNotCanonical, v)
+	case string:
+		return jsonvalue.AppendString(buf, v), nil
+	case []any:
+		buf = append(buf, '[')
+		for i, element := range v {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			var err error
+			buf, err = appendCanonical(buf, element)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, ']'), nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		// Byte order of UTF-8 is the same as code point order
+		slices.Sort(keys)
+
+		buf = append(buf, '{')
+		for i, key := range keys {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = jsonvalue.AppendString(buf, key)
+			buf = append(buf, ':')
+			var err error
+			buf, err = appendCanonical(buf, v[key])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, '}'), nil
+	case jsonvalue.Object:
+		members, err := objectMap(v)
+		if err != nil {
+			return nil, err
+		}
+		return appendCanonical(buf, members)
+	default:
+		return nil, fmt.Errorf("%w: unsupported type %T", ErrNotCanonical, value)
+	}
+}
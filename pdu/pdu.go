@@ -0,0 +1,329 @@
+// Package pdu gives access to the fields of a types.Transaction_PDU independent of its room version.
+//
+// The schema has one group per room version in the union of Transaction.PDU. The groups mostly share their fields,
+// but room versions 1 and 2 carry the event ID and reference events together with their hashes, and room version 11
+// moved `redacts` into the content. View hides these differences behind the PDU interface and Build writes the group
+// of a given room version.
+package pdu
+
+import (
+	"errors"
+	"fmt"
+
+	capnp "capnproto.org/go/capnp/v3"
+
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// ErrUnknownRoomVersion is returned for PDUs of a room version this package does not know.
+var ErrUnknownRoomVersion = errors.New("pdu: unknown room version")
+
+// ErrNotInRoomVersion is returned when accessing a field the room version of the PDU does not have.
+var ErrNotInRoomVersion = errors.New("pdu: field does not exist in this room version")
+
+// EventReference references another event.
+//
+// Room versions 1 and 2 reference events as `[event_id, {"sha256": hash}]` pairs. On the wire a pair is an eventID
+// entry, optionally followed by a sha256 entry in the List(EventReference). Later room versions only use the ID.
+type EventReference struct {
+	EventID string
+	// Unpadded base64 sha256 reference hash of the event. Empty if unknown or not used by the room version.
+	SHA256 string
+}
+
+// PDU is a PDU of any room version
+type PDU interface {
+	// Struct returns the underlying capnproto struct
+	Struct() types.Transaction_PDU
+	// RoomVersion returns the room version, e.g. "1" or "11"
+	RoomVersion() string
+
+	Depth() uint64
+	OriginServerTS() int64
+	RoomID() (string, error)
+	Sender() (string, error)
+	Type() (string, error)
+	HasStateKey() bool
+	StateKey() (string, error)
+	HasContent() bool
+	Content() (types.JsonValue, error)
+	Hashes() (capnp.TextList, error)
+	Signatures() (types.Signature_List, error)
+	HasUnsigned() bool
+	Unsigned() (types.Transaction_PDU_Unsigned, error)
+
+	// The deprecated top level keys origin, membership and prev_state. Room version 11 no longer keeps them in the
+	// redacted form of an event.
+	HasOrigin() bool
+	Origin() (string, error)
+	HasMembership() bool
+	Membership() (string, error)
+	HasPrevState() bool
+	PrevState() (types.JsonValue, error)
+
+	// EventID returns the event ID sent with the PDU. Only room versions 1 and 2 have one, later room versions derive
+	// it from the reference hash.
+	EventID() (string, error)
+	// HasRedacts reports whether the PDU has a top level redacts key. It is part of the content from room version 11.
+	HasRedacts() bool
+	Redacts() (string, error)
+
+	// AuthEvents returns the IDs of the auth events
+	AuthEvents() ([]string, error)
+	// PrevEvents returns the IDs of the prev events
+	PrevEvents() ([]string, error)
+	// AuthEventReferences returns the auth events including their hashes in room versions 1 and 2
+	AuthEventReferences() ([]EventReference, error)
+	// PrevEventReferences returns the prev events including their hashes in room versions 1 and 2
+	PrevEventReferences() ([]EventReference, error)
+}
+
+// common are the fields all room versions share
+type common interface {
+	Depth() uint64
+	SetDepth(v uint64)
+	OriginServerTS() int64
+	SetOriginServerTS(v int64)
+	RoomID() (string, error)
+	SetRoomID(v string) error
+	Sender() (string, error)
+	SetSender(v string) error
+	Type() (string, error)
+	SetType(v string) error
+	HasStateKey() bool
+	StateKey() (string, error)
+	SetStateKey(v string) error
+	HasContent() bool
+	Content() (types.JsonValue, error)
+	SetContent(v types.JsonValue) error
+	NewContent() (types.JsonValue, error)
+	Hashes() (capnp.TextList, error)
+	SetHashes(v capnp.TextList) error
+	NewHashes(n int32) (capnp.TextList, error)
+	Signatures() (types.Signature_List, error)
+	SetSignatures(v types.Signature_List) error
+	NewSignatures(n int32) (types.Signature_List, error)
+	HasUnsigned() bool
+	Unsigned() (types.Transaction_PDU_Unsigned, error)
+	NewUnsigned() (types.Transaction_PDU_Unsigned, error)
+	HasOrigin() bool
+	Origin() (string, error)
+	SetOrigin(v string) error
+	HasMembership() bool
+	Membership() (string, error)
+	SetMembership(v string) error
+	HasPrevState() bool
+	PrevState() (types.JsonValue, error)
+	SetPrevState(v types.JsonValue) error
+	NewPrevState() (types.JsonValue, error)
+}
+
+// withEventID is implemented by room versions 1 and 2
+type withEventID interface {
+	EventID() (string, error)
+	SetEventID(v string) error
+}
+
+// withRedacts is implemented by room versions 1 to 10
+type withRedacts interface {
+	HasRedacts() bool
+	Redacts() (string, error)
+	SetRedacts(v string) error
+}
+
+// textEdges is implemented by room versions 3 and later
+type textEdges interface {
+	AuthEvents() (capnp.TextList, error)
+	NewAuthEvents(n int32) (capnp.TextList, error)
+	PrevEvents() (capnp.TextList, error)
+	NewPrevEvents(n int32) (capnp.TextList, error)
+}
+
+// referenceEdges is implemented by room versions 1 and 2
+type referenceEdges interface {
+	AuthEvents() (types.Transaction_PDU_EventReference_List, error)
+	NewAuthEvents(n int32) (types.Transaction_PDU_EventReference_List, error)
+	PrevEvents() (types.Transaction_PDU_EventReference_List, error)
+	NewPrevEvents(n int32) (types.Transaction_PDU_EventReference_List, error)
+}
+
+// view implements PDU over the group of the room version
+type view struct {
+	common
+	pdu     types.Transaction_PDU
+	version string
+	// Fields that only some room versions have. Nil if the room version does not have them.
+	event_id   withEventID
+	redacts    withRedacts
+	text       textEdges
+	references referenceEdges
+}
+
+// View returns the PDU interface of a PDU
+func View(pdu types.Transaction_PDU) (PDU, error) {
+	return newView(pdu)
+}
+
+func newView(pdu types.Transaction_PDU) (*view, error) {
+	switch pdu.Which() {
+	case types.Transaction_PDU_Which_roomVersion1:
+		g := pdu.RoomVersion1()
+		return &view{common: g, pdu: pdu, version: "1", event_id: g, redacts: g, references: g}, nil
+	case types.Transaction_PDU_Which_roomVersion2:
+		g := pdu.RoomVersion2()
+		return &view{common: g, pdu: pdu, version: "2", event_id: g, redacts: g, references: g}, nil
+	case types.Transaction_PDU_Which_roomVersion3:
+		g := pdu.RoomVersion3()
+		return &view{common: g, pdu: pdu, version: "3", redacts: g, text: g}, nil
+	case types.Transaction_PDU_Which_roomVersion4:
+		g := pdu.RoomVersion4()
+		return &view{common: g, pdu: pdu, version: "4", redacts: g, text: g}, nil
+	case types.Transaction_PDU_Which_roomVersion5:
+		g := pdu.RoomVersion5()
+		return &view{common: g, pdu: pdu, version: "5", redacts: g, text: g}, nil
+	case types.Transaction_PDU_Which_roomVersion6:
+		g := pdu.RoomVersion6()
+		return &view{common: g, pdu: pdu, version: "6", redacts: g, text: g}, nil
+	case types.Transaction_PDU_Which_roomVersion7:
+		g := pdu.RoomVersion7()
+		return &view{common: g, pdu: pdu, version: "7", redacts: g, text: g}, nil
+	case types.Transaction_PDU_Which_roomVersion8:
+		g := pdu.RoomVersion8()
+		return &view{common: g, pdu: pdu, version: "8", redacts: g, text: g}, nil
+	case types.Transaction_PDU_Which_roomVersion9:
+		g := pdu.RoomVersion9()
+		return &view{common: g, pdu: pdu, version: "9", redacts: g, text: g}, nil
+	case types.Transaction_PDU_Which_roomVersion10:
+		g := pdu.RoomVersion10()
+		return &view{common: g, pdu: pdu, version: "10", redacts: g, text: g}, nil
+	case types.Transaction_PDU_Which_roomVersion11:
+		g := pdu.RoomVersion11()
+		return &view{common: g, pdu: pdu, version: "11", text: g}, nil
+	default:
+		return nil, fmt.Errorf("%w: union member %d", ErrUnknownRoomVersion, pdu.Which())
+	}
+}
+
+func (v *view) Struct() types.Transaction_PDU {
+	return v.pdu
+}
+
+func (v *view) RoomVersion() string {
+	return v.version
+}
+
+func (v *view) EventID() (string, error) {
+	if v.event_id == nil {
+		return "", fmt.Errorf("%w: event ID, room version %s", ErrNotInRoomVersion, v.version)
+	}
+	return v.event_id.EventID()
+}
+
+func (v *view) HasRedacts() bool {
+	return v.redacts != nil && v.redacts.HasRedacts()
+}
+
+func (v *view) Redacts() (string, error) {
+	if v.redacts == nil {
+		return "", fmt.Errorf("%w: redacts, room version %s", ErrNotInRoomVersion, v.version)
+	}
+	return v.redacts.Redacts()
+}
+
+func (v *view) AuthEvents() ([]string, error) {
+	if v.text != nil {
+		return textList(v.text.AuthEvents())
+	}
+	return referenceIDs(v.references.AuthEvents())
+}
+
+func (v *view) PrevEvents() ([]string, error) {
+	if v.text != nil {
+		return textList(v.text.PrevEvents())
+	}
+	return referenceIDs(v.references.PrevEvents())
+}
+
+func (v *view) AuthEventReferences() ([]EventReference, error) {
+	if v.text != nil {
+		return textReferences(v.text.AuthEvents())
+	}
+	return readReferences(v.references.AuthEvents())
+}
+
+func (v *view) PrevEventReferences() ([]EventReference, error) {
+	if v.text != nil {
+		return textReferences(v.text.PrevEvents())
+	}
+	return readReferences(v.references.PrevEvents())
+}
+
+func textList(list capnp.TextList, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	strings := make([]string, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		s, err := list.At(i)
+		if err != nil {
+			return nil, err
+		}
+		strings = append(strings, s)
+	}
+	return strings, nil
+}
+
+func textReferences(list capnp.TextList, err error) ([]EventReference, error) {
+	event_ids, err := textList(list, err)
+	if err != nil {
+		return nil, err
+	}
+	references := make([]EventReference, len(event_ids))
+	for i, event_id := range event_ids {
+		references[i].EventID = event_id
+	}
+	return references, nil
+}
+
+func referenceIDs(list types.Transaction_PDU_EventReference_List, err error) ([]string, error) {
+	references, err := readReferences(list, err)
+	if err != nil {
+		return nil, err
+	}
+	event_ids := make([]string, len(references))
+	for i, reference := range references {
+		event_ids[i] = reference.EventID
+	}
+	return event_ids, nil
+}
+
+// readReferences reads a list of eventID entries that are each optionally followed by a sha256 entry
+func readReferences(list types.Transaction_PDU_EventReference_List, err error) ([]EventReference, error) {
+	if err != nil {
+		return nil, err
+	}
+	var references []EventReference
+	for i := 0; i < list.Len(); i++ {
+		entry := list.At(i)
+		switch entry.Which() {
+		case types.Transaction_PDU_EventReference_Which_eventID:
+			event_id, err := entry.EventID()
+			if err != nil {
+				return nil, err
+			}
+			references = append(references, EventReference{EventID: event_id})
+		case types.Transaction_PDU_EventReference_Which_sha256:
+			if len(references) == 0 || references[len(references)-1].SHA256 != "" {
+				return nil, fmt.Errorf("sha256 entry %d does not follow an event ID", i)
+			}
+			hash, err := entry.Sha256()
+			if err != nil {
+				return nil, err
+			}
+			references[len(references)-1].SHA256 = hash
+		default:
+			return nil, fmt.Errorf("unknown event reference entry %d", entry.Which())
+		}
+	}
+	return references, nil
+}
@@ -0,0 +1,459 @@
+package pdu
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	capnp "capnproto.org/go/capnp/v3"
+
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// redactionRules are the differences of the redaction algorithm between room versions.
+// See https://spec.matrix.org/v1.9/rooms/ for the algorithm of each room version.
+type redactionRules struct {
+	// m.room.aliases keeps its aliases. Dropped in room version 6.
+	keep_aliases bool
+	// m.room.join_rules keeps allow. Added in room version 8.
+	keep_allow bool
+	// m.room.member keeps join_authorised_via_users_server. Added in room version 9.
+	keep_authorised_via bool
+	// Room version 11 keeps the whole content of m.room.create, invite of m.room.power_levels,
+	// third_party_invite.signed of m.room.member and redacts of m.room.redaction. It drops the top level origin,
+	// membership and prev_state.
+	v11 bool
+}
+
+func rulesFor(roomVersion string) (redactionRules, error) {
+	switch roomVersion {
+	case "1", "2", "3", "4", "5":
+		return redactionRules{keep_aliases: true}, nil
+	case "6", "7":
+		return redactionRules{}, nil
+	case "8":
+		return redactionRules{keep_allow: true}, nil
+	case "9", "10":
+		return redactionRules{keep_allow: true, keep_authorised_via: true}, nil
+	case "11":
+		return redactionRules{keep_allow: true, keep_authorised_via: true, v11: true}, nil
+	default:
+		return redactionRules{}, fmt.Errorf("%w: %q", ErrUnknownRoomVersion, roomVersion)
+	}
+}
+
+// contentKeys returns the content keys of an event type that survive a redaction.
+// all is true if the whole content is kept.
+func (r redactionRules) contentKeys(eventType string) (keys []string, all bool) {
+	switch eventType {
+	case "m.room.member":
+		keys = []string{"membership"}
+		if r.keep_authorised_via {
+			keys = append(keys, "join_authorised_via_users_server")
+		}
+		if r.v11 {
+			keys = append(keys, "third_party_invite")
+		}
+	case "m.room.create":
+		if r.v11 {
+			return nil, true
+		}
+		keys = []string{"creator"}
+	case "m.room.join_rules":
+		keys = []string{"join_rule"}
+		if r.keep_allow {
+			keys = append(keys, "allow")
+		}
+	case "m.room.power_levels":
+		keys = []string{"ban", "events", "events_default", "kick", "redact", "state_default", "users", "users_default"}
+		if r.v11 {
+			keys = append(keys, "invite")
+		}
+	case "m.room.aliases":
+		if r.keep_aliases {
+			keys = []string{"aliases"}
+		}
+	case "m.room.history_visibility":
+		keys = []string{"history_visibility"}
+	case "m.room.redaction":
+		if r.v11 {
+			keys = []string{"redacts"}
+		}
+	}
+	return keys, false
+}
+
+// Redact returns the redacted form of the PDU in a new message.
+//
+// Only the fields and content keys the redaction algorithm of the room version keeps are copied. The top level
+// redacts and unsigned are dropped in every room version, origin, membership and prev_state from room version 11.
+// Objects in the content are written with their keys sorted, so two PDUs with the same content in a different order
+// have the same redacted form.
+func Redact(p PDU) (PDU, error) {
+	return redact(p)
+}
+
+// RedactedUnsigned returns the redacted PDU without its signatures in a new message. This is the part of a PDU its
+// signatures cover.
+func RedactedUnsigned(p PDU) (PDU, error) {
+	redacted, err := redact(p)
+	if err != nil {
+		return nil, err
+	}
+	err = redacted.SetSignatures(types.Signature_List{})
+	if err != nil {
+		return nil, err
+	}
+	return redacted, nil
+}
+
+// RedactedCanonical returns the canonical capnproto encoding of RedactedUnsigned.
+// These are the bytes to hash or sign when working on the capnproto form of a PDU.
+func RedactedCanonical(p PDU) ([]byte, error) {
+	redacted, err := RedactedUnsigned(p)
+	if err != nil {
+		return nil, err
+	}
+	return capnp.Canonicalize(capnp.Struct(redacted.Struct()))
+}
+
+func redact(p PDU) (*Builder, error) {
+	rules, err := rulesFor(p.RoomVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return nil, err
+	}
+	root, err := types.NewRootTransaction_PDU(seg)
+	if err != nil {
+		return nil, err
+	}
+	b, err := Build(root, p.RoomVersion())
+	if err != nil {
+		return nil, err
+	}
+
+	b.SetDepth(p.Depth())
+	b.SetOriginServerTS(p.OriginServerTS())
+	room_id, err := p.RoomID()
+	if err != nil {
+		return nil, err
+	}
+	err = b.SetRoomID(room_id)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := p.Sender()
+	if err != nil {
+		return nil, err
+	}
+	err = b.SetSender(sender)
+	if err != nil {
+		return nil, err
+	}
+	event_type, err := p.Type()
+	if err != nil {
+		return nil, err
+	}
+	err = b.SetType(event_type)
+	if err != nil {
+		return nil, err
+	}
+	if p.HasStateKey() {
+		state_key, err := p.StateKey()
+		if err != nil {
+			return nil, err
+		}
+		err = b.SetStateKey(state_key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if b.event_id != nil {
+		event_id, err := p.EventID()
+		if err != nil {
+			return nil, err
+		}
+		err = b.SetEventID(event_id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !rules.v11 {
+		err = redactLegacyKeys(b, p)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	auth_events, err := p.AuthEventReferences()
+	if err != nil {
+		return nil, err
+	}
+	err = b.SetAuthEventReferences(auth_events)
+	if err != nil {
+		return nil, err
+	}
+	prev_events, err := p.PrevEventReferences()
+	if err != nil {
+		return nil, err
+	}
+	err = b.SetPrevEventReferences(prev_events)
+	if err != nil {
+		return nil, err
+	}
+
+	// Setting a pointer from another message copies it
+	hashes, err := p.Hashes()
+	if err != nil {
+		return nil, err
+	}
+	err = b.SetHashes(hashes)
+	if err != nil {
+		return nil, err
+	}
+	signatures, err := p.Signatures()
+	if err != nil {
+		return nil, err
+	}
+	err = b.SetSignatures(signatures)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := b.NewContent()
+	if err != nil {
+		return nil, err
+	}
+	err = redactContent(content, p, rules, event_type)
+	if err != nil {
+		return nil, fmt.Errorf("content: %w", err)
+	}
+	return b, nil
+}
+
+// redactLegacyKeys copies the top level origin, membership and prev_state, which room versions 1 to 10 keep
+func redactLegacyKeys(b *Builder, p PDU) error {
+	if p.HasOrigin() {
+		origin, err := p.Origin()
+		if err != nil {
+			return err
+		}
+		err = b.SetOrigin(origin)
+		if err != nil {
+			return err
+		}
+	}
+	if p.HasMembership() {
+		membership, err := p.Membership()
+		if err != nil {
+			return err
+		}
+		err = b.SetMembership(membership)
+		if err != nil {
+			return err
+		}
+	}
+	if p.HasPrevState() {
+		prev_state, err := p.PrevState()
+		if err != nil {
+			return err
+		}
+		dst, err := b.NewPrevState()
+		if err != nil {
+			return err
+		}
+		err = copySorted(dst, prev_state)
+		if err != nil {
+			return fmt.Errorf("prev_state: %w", err)
+		}
+	}
+	return nil
+}
+
+// redactContent writes the content keys of p the rules keep to dst
+func redactContent(dst types.JsonValue, p PDU, rules redactionRules, eventType string) error {
+	if !p.HasContent() {
+		_, err := dst.NewObject(0)
+		return err
+	}
+	content, err := p.Content()
+	if err != nil {
+		return err
+	}
+
+	keys, all := rules.contentKeys(eventType)
+	if all {
+		return copySorted(dst, content)
+	}
+	if content.Which() != types.JsonValue_Which_object {
+		_, err := dst.NewObject(0)
+		return err
+	}
+
+	fields, err := sortedFields(content)
+	if err != nil {
+		return err
+	}
+	fields = slices.DeleteFunc(fields, func(f field) bool {
+		if f.name == "third_party_invite" && f.value.Which() != types.JsonValue_Which_object {
+			return true
+		}
+		return !slices.Contains(keys, f.name)
+	})
+
+	list, err := dst.NewObject(int32(len(fields)))
+	if err != nil {
+		return err
+	}
+	for i, f := range fields {
+		err = list.At(i).SetName(f.name)
+		if err != nil {
+			return err
+		}
+		value, err := list.At(i).NewValue()
+		if err != nil {
+			return err
+		}
+		if f.name == "third_party_invite" {
+			// Only the signed part of a third party invite survives
+			err = redactThirdPartyInvite(value, f.value)
+		} else {
+			err = copySorted(value, f.value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func redactThirdPartyInvite(dst types.JsonValue, invite types.JsonValue) error {
+	fields, err := sortedFields(invite)
+	if err != nil {
+		return err
+	}
+	signed := slices.DeleteFunc(fields, func(f field) bool {
+		return f.name != "signed"
+	})
+
+	list, err := dst.NewObject(int32(len(signed)))
+	if err != nil {
+		return err
+	}
+	for i, f := range signed {
+		err = list.At(i).SetName(f.name)
+		if err != nil {
+			return err
+		}
+		value, err := list.At(i).NewValue()
+		if err != nil {
+			return err
+		}
+		err = copySorted(value, f.value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// field is a key of a JSON object
+type field struct {
+	name  string
+	value types.JsonValue
+}
+
+// sortedFields returns the fields of an object sorted by name. Duplicate names are rejected.
+func sortedFields(object types.JsonValue) ([]field, error) {
+	list, err := object.Object()
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]field, list.Len())
+	for i := range fields {
+		fields[i].name, err = list.At(i).Name()
+		if err != nil {
+			return nil, err
+		}
+		fields[i].value, err = list.At(i).Value()
+		if err != nil {
+			return nil, err
+		}
+	}
+	slices.SortFunc(fields, func(a, b field) int {
+		return strings.Compare(a.name, b.name)
+	})
+	for i := 1; i < len(fields); i++ {
+		if fields[i].name == fields[i-1].name {
+			return nil, fmt.Errorf("%w: duplicate key %q", ErrNotCanonical, fields[i].name)
+		}
+	}
+	return fields, nil
+}
+
+// copySorted copies a JSON value with the keys of all objects sorted
+func copySorted(dst types.JsonValue, src types.JsonValue) error {
+	switch src.Which() {
+	case types.JsonValue_Which_null:
+		dst.SetNull()
+	case types.JsonValue_Which_boolean:
+		dst.SetBoolean(src.Boolean())
+	case types.JsonValue_Which_integer:
+		dst.SetInteger(src.Integer())
+	case types.JsonValue_Which_number:
+		dst.SetNumber(src.Number())
+	case types.JsonValue_Which_string_:
+		s, err := src.String_()
+		if err != nil {
+			return err
+		}
+		return dst.SetString_(s)
+	case types.JsonValue_Which_array:
+		array, err := src.Array()
+		if err != nil {
+			return err
+		}
+		list, err := dst.NewArray(int32(array.Len()))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < array.Len(); i++ {
+			err = copySorted(list.At(i), array.At(i))
+			if err != nil {
+				return err
+			}
+		}
+	case types.JsonValue_Which_object:
+		fields, err := sortedFields(src)
+		if err != nil {
+			return err
+		}
+		list, err := dst.NewObject(int32(len(fields)))
+		if err != nil {
+			return err
+		}
+		for i, f := range fields {
+			err = list.At(i).SetName(f.name)
+			if err != nil {
+				return err
+			}
+			value, err := list.At(i).NewValue()
+			if err != nil {
+				return err
+			}
+			err = copySorted(value, f.value)
+			if err != nil {
+				return err
+			}
+		}
+	case types.JsonValue_Which_call:
+		return fmt.Errorf("%w: call values are not JSON", ErrNotCanonical)
+	default:
+		return fmt.Errorf("unknown JSON value %d", src.Which())
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package pdu
+
+import (
+	"sync"
+
+	capnp "capnproto.org/go/capnp/v3"
+	"capnproto.org/go/capnp/v3/schemas"
+	"capnproto.org/go/capnp/v3/std/capnp/schema"
+
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// stateKeyPointers maps the union member of each room version to the pointer index of stateKey in its group.
+// It is read from the schema embedded in the generated code, see Builder.SetStateKey.
+var stateKeyPointers = sync.OnceValues(func() (map[uint16]uint16, error) {
+	return groupPointers(types.Transaction_PDU_TypeID, "stateKey")
+})
+
+// originPointers are the pointer indexes of origin, see stateKeyPointers
+var originPointers = sync.OnceValues(func() (map[uint16]uint16, error) {
+	return groupPointers(types.Transaction_PDU_TypeID, "origin")
+})
+
+// membershipPointers are the pointer indexes of membership, see stateKeyPointers
+var membershipPointers = sync.OnceValues(func() (map[uint16]uint16, error) {
+	return groupPointers(types.Transaction_PDU_TypeID, "membership")
+})
+
+// groupPointers returns the pointer index of the field with the given name in every group of the union of a
+// struct, keyed by the discriminant of the group.
+func groupPointers(structID uint64, fieldName string) (map[uint16]uint16, error) {
+	var registry schemas.Registry
+	types.RegisterSchema(&registry)
+	data, err := registry.Find(structID)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := capnp.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	request, err := schema.ReadRootCodeGeneratorRequest(msg)
+	if err != nil {
+		return nil, err
+	}
+	node_list, err := request.Nodes()
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[uint64]schema.Node, node_list.Len())
+	for i := 0; i < node_list.Len(); i++ {
+		nodes[node_list.At(i).Id()] = node_list.At(i)
+	}
+
+	groups, err := nodes[structID].StructNode().Fields()
+	if err != nil {
+		return nil, err
+	}
+	pointers := make(map[uint16]uint16)
+	for i := 0; i < groups.Len(); i++ {
+		group := groups.At(i)
+		if group.Which() != schema.Field_Which_group || group.DiscriminantValue() == schema.Field_noDiscriminant {
+			continue
+		}
+		fields, err := nodes[group.Group().TypeId()].StructNode().Fields()
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < fields.Len(); j++ {
+			name, err := fields.At(j).Name()
+			if err != nil {
+				return nil, err
+			}
+			if name == fieldName && fields.At(j).Which() == schema.Field_Which_slot {
+				pointers[group.DiscriminantValue()] = uint16(fields.At(j).Slot().Offset())
+			}
+		}
+	}
+	return pointers, nil
+}
@@ -0,0 +1,116 @@
+package pdu
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// ErrInvalidID is returned if a user or event ID has no server name
+var ErrInvalidID = errors.New("pdu: ID without server name")
+
+// RequiredSigners returns the servers that must have signed the PDU as described in
+// https://spec.matrix.org/v1.9/server-server-api/#validating-hashes-and-signatures-on-received-events
+//
+// These are the server of the sender, the server that created the event ID in room versions 1 and 2 and, for joins
+// authorised by a user of another server in room version 8 and later, the server of that user.
+// The signatures cover the bytes returned by RedactedCanonical.
+func RequiredSigners(p PDU) ([]string, error) {
+	sender, err := p.Sender()
+	if err != nil {
+		return nil, err
+	}
+	sender_server, err := ServerName(sender)
+	if err != nil {
+		return nil, err
+	}
+	servers := []string{sender_server}
+
+	if p.RoomVersion() == "1" || p.RoomVersion() == "2" {
+		event_id, err := p.EventID()
+		if err != nil {
+			return nil, err
+		}
+		event_id_server, err := ServerName(event_id)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, event_id_server)
+	}
+
+	authorised_via, err := joinAuthorisedVia(p)
+	if err != nil {
+		return nil, err
+	}
+	if authorised_via != "" {
+		authorising_server, err := ServerName(authorised_via)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, authorising_server)
+	}
+
+	slices.Sort(servers)
+	return slices.Compact(servers), nil
+}
+
+// ServerName returns the server name of a user, room or room version 1 and 2 event ID, which is everything after the
+// first colon. See https://spec.matrix.org/v1.9/appendices/#common-identifier-format
+func ServerName(id string) (string, error) {
+	_, server, ok := strings.Cut(id, ":")
+	if !ok || server == "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidID, id)
+	}
+	return server, nil
+}
+
+// joinAuthorisedVia returns the join_authorised_via_users_server of a join in room version 8 and later or an empty
+// string if there is none
+func joinAuthorisedVia(p PDU) (string, error) {
+	switch p.RoomVersion() {
+	case "1", "2", "3", "4", "5", "6", "7":
+		return "", nil
+	}
+	event_type, err := p.Type()
+	if err != nil || event_type != "m.room.member" || !p.HasContent() {
+		return "", err
+	}
+	content, err := p.Content()
+	if err != nil {
+		return "", err
+	}
+	membership, err := stringField(content, "membership")
+	if err != nil || membership != "join" {
+		return "", err
+	}
+	return stringField(content, "join_authorised_via_users_server")
+}
+
+// stringField returns the value of a key of an object if it is a string. Anything else results in an empty string.
+func stringField(object types.JsonValue, name string) (string, error) {
+	if object.Which() != types.JsonValue_Which_object {
+		return "", nil
+	}
+	fields, err := object.Object()
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < fields.Len(); i++ {
+		field_name, err := fields.At(i).Name()
+		if err != nil {
+			return "", err
+		}
+		if field_name != name {
+			continue
+		}
+		value, err := fields.At(i).Value()
+		if err != nil || value.Which() != types.JsonValue_Which_string_ {
+			return "", err
+		}
+		return value.String_()
+	}
+	return "", nil
+}
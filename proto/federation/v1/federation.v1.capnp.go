@@ -490,7 +490,7 @@ func (c MatrixFederation) SendTransactions(ctx context.Context, params func(Matr
 		},
 	}
 	if params != nil {
-		s.ArgsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 0}
+		s.ArgsSize = capnp.ObjectSize{DataSize: 0, PointerCount: 1}
 		s.PlaceArgs = func(s capnp.Struct) error { return params(MatrixFederation_sendTransactions_Params(s)) }
 	}
 
@@ -1087,12 +1087,12 @@ type MatrixFederation_sendTransactions_Params capnp.Struct
 const MatrixFederation_sendTransactions_Params_TypeID = 0xff6b4b94e55e5d73
 
 func NewMatrixFederation_sendTransactions_Params(s *capnp.Segment) (MatrixFederation_sendTransactions_Params, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
 	return MatrixFederation_sendTransactions_Params(st), err
 }
 
 func NewRootMatrixFederation_sendTransactions_Params(s *capnp.Segment) (MatrixFederation_sendTransactions_Params, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1})
 	return MatrixFederation_sendTransactions_Params(st), err
 }
 
@@ -1128,13 +1128,30 @@ func (s MatrixFederation_sendTransactions_Params) Message() *capnp.Message {
 func (s MatrixFederation_sendTransactions_Params) Segment() *capnp.Segment {
 	return capnp.Struct(s).Segment()
 }
+func (s MatrixFederation_sendTransactions_Params) Results() StreamCallback {
+	p, _ := capnp.Struct(s).Ptr(0)
+	return StreamCallback(p.Interface().Client())
+}
+
+func (s MatrixFederation_sendTransactions_Params) HasResults() bool {
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s MatrixFederation_sendTransactions_Params) SetResults(v StreamCallback) error {
+	if !v.IsValid() {
+		return capnp.Struct(s).SetPtr(0, capnp.Ptr{})
+	}
+	seg := s.Segment()
+	in := capnp.NewInterface(seg, seg.Message().CapTable().Add(capnp.Client(v)))
+	return capnp.Struct(s).SetPtr(0, in.ToPtr())
+}
 
 // MatrixFederation_sendTransactions_Params_List is a list of MatrixFederation_sendTransactions_Params.
 type MatrixFederation_sendTransactions_Params_List = capnp.StructList[MatrixFederation_sendTransactions_Params]
 
 // NewMatrixFederation_sendTransactions_Params creates a new list of MatrixFederation_sendTransactions_Params.
 func NewMatrixFederation_sendTransactions_Params_List(s *capnp.Segment, sz int32) (MatrixFederation_sendTransactions_Params_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 0}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 1}, sz)
 	return capnp.StructList[MatrixFederation_sendTransactions_Params](l), err
 }
 
@@ -1145,6 +1162,9 @@ func (f MatrixFederation_sendTransactions_Params_Future) Struct() (MatrixFederat
 	p, err := f.Future.Ptr()
 	return MatrixFederation_sendTransactions_Params(p.Struct()), err
 }
+func (p MatrixFederation_sendTransactions_Params_Future) Results() StreamCallback {
+	return StreamCallback(p.Future.Field(0, nil).Client())
+}
 
 type MatrixFederation_sendTransactions_Results capnp.Struct
 
@@ -1457,81 +1477,83 @@ func (f MatrixFederation_backfill_Results_Future) Struct() (MatrixFederation_bac
 	return MatrixFederation_backfill_Results(p.Struct()), err
 }
 
-const schema_ee8fadeb6a9300eb = "x\xda\xacVkl\x14U\x14>\xdf\xbdSf\x17v" +
-	"\x97\xbd\xdd\x12\xc5DV\x91\xc4bl\xcb\xb21\x08\xfc" +
-	"\xa0\x85\x02\xb6\x84\xb8\x97R\x12\x08\xd0\x0c\xed\x00K\xf7" +
-	"\x01;\xd3\x16\x82(\"\x8d\x82\xe5Q) D~\x88" +
-	"\xe2\x0bID\x12c\x10\x15\x02H0\xd1\xa4>\x121" +
-	"\xf1\xc1\x0f\x0c\x09h\xa2\xfe0*2\xe6Nw\xb6[" +
-	"\xb5\x11\xd2\xfe\xd9d\xe7;\xf7<\xbe\xef\xdes\xce\xa4" +
-	"\xd9Z\xb5\x16\x0b\xee/%&\xbf*\x19\xe1|vb" +
-	">\xfba\xe7\xc1'I\x94\xf3\xbf\xae\xedYs\xed\xd8" +
-	"\xae\x9f\x88\xc2\x88\xc7\xfc'\x11\xa9\xf3\xebD\x91\xd9\xfe" +
-	"\xa7#\xbd~=\xd2\xeb\x1f\xedly4\xb1\x9cu\xdc" +
-	"\xddEb*\x884\x9d(~\xde\xbf\x80\x91\xe6\xdc[" +
-	"\xca\x8f\xbf\xfbz\xc5\xee\"\xe4\x8c\xbf^!\xc6Y\xfd" +
-	"\xfc\xb2\xc9\xe3\xf7\xf5!%P\xd0\xfb\xfe\xe7\x18!\xf2" +
-	"\x8d\x7f\x06\xc19V\xf5\xd0\xc9\xa5c_\xd8\x9f7`" +
-	"\xca 6r\xa62\xa8\x1b\xd9Ap\xbe\xbb\xf8\xf3\x8d" +
-	"\x9e\x85s_)\xf2\x9d\x1e\xb5D\xf9\x9e\xf2\xf6\xa2T" +
-	"\xea\x8b\xfb\xce\x91\x9c\x0au\xd6\xc5\x92\xa3\xea\x19!\xde" +
-	"9*\x0a\x82s\xcf\xd5\x8a\xda\x86\xc5\x87.\x92\x98\xd2" +
-	"\x17>\x8c\xf8\xe5\xc0x\xe5\xfd\xf7\x80\x0a/\x9f?k" +
-	"\x94^\xd8\xf8y\x9f\x81\xa6\xf0\xb1\xc1\xf1\xca\xfb\xac\xcd" +
-	"\x8f\xbf\xd4\xbb\xbb\xf1\x0au\x97\x97\xb0\x02=\x88\x8b\xe0" +
-	"AD&\x06u\xa2\x86\x09A\xae\x82lI7\xf8n" +
-	"65\xfdZ\\\xe3\x98\xe0\x0a\x15$\x16TA\xc6\x1d" +
-	"\x98;\xfd\xd9\xdaI\xbf\x0d \x1a\xf1\xc6\xe0\xc7\x88\xac" +
-	"\x0b\xba\x15\x05uD\x0e\x85t\"\xc7Z\xb6\xfcJ\xcf" +
-	"\xbcV\xa7\xa8\xde\xce\xd0v\x95\xd1\x9b\xeb\xc7n\xac\xec" +
-	"\xfa\xc3)\xca\xb53t\x17#\x87N9ksY;" +
-	"[\xb5\xd2,i1s\x86\x9d\xccf\xaa\xdacU+" +
-	"M\xef_e{\xac\xb2\xd9X\x9bY;\xad\xc1\xce\x99" +
-	"Fz\x961#\x95Za4\xb7&\x00\xe9\xe3%D" +
-	"\x05\xaa\x909~\xba#~\xb0\xe9\x80\x88M&\xaay" +
-	"\x10Db\xaa\x0e\x14\xc2\xc3\xe3LT<@TS\x8e" +
-	"\x9a\x87!\xea\xf4hG.i\x9b\x02Q\xa91\xf4\xdf" +
-	"-\xa5L5F\xb7d3\xff\x8d\x0dr \x01\xd4h" +
-	"\x10(\xc5\xc2Bm\xbe\xff\xabm\xbea\xe7\x92\xeb\xe7" +
-	"\xf4#\xaa\xc2\x95\xc9Tj\xc2\x02\xd3jK\xd9\xb0\x86" +
-	"\xe0k\x95i\xcf37XyW\x16Q\xc1\x97\xff\xb6" +
-	"}Yf\xa6ea\xce\xc8XF\xb3\xfao\x15\xf2\x93" +
-	"\x1a\xd7\x884\xc5K\xb0\x9eH\x068\xe4#\x0cN\xb3" +
-	"\xd1'\x17\x11A\xf4s\xa5\x98\xfa\x17\x81\x8a\xd3;\xa4" +
-	"\x06\x00\x09\x0e\x84\x9d\xc5\x9ft\x9c\x9bS}j+\x11" +
-	" \x08\xc3\xc0A\xc2\xc8\x19i\x8b\xa4\xaf\x90\xed\xc4\x15" +
-	"D\xb2\x9cC\x9ec\x10@\x99+\xed\x19U\xc2i\x0e" +
-	"y\x83\xc1\xb1\xcc\\\xbb\x99kj%\xdd\xdc`!\xec" +
-	"D.|xd\xcf\xb8\x8e7\x06\x14Q\xf4Q\xe0~" +
-	"\xe9SEH\x1fW\xbfa\x0e\x04\x88\x0d\xe9dM\xe2" +
-	"z\xcb\xbc\xc5\x1f\xbd\xa7\x98\x08\xd3\x10i\xed\x89\xbd\xba" +
-	"\x86\xf5\x9c\xea\x1e\x16Z\x17\x999+\x99\xcd\xb8\xcc\xf2" +
-	"\xb45\x1cw>/\x12\xc9\xb2\x82J\x9b\x16\x10\xc9\xc7" +
-	"8\xe43E*uN#\x92\x9b9d\x17\x03X\x19" +
-	"\x18\x91\xd86\x99Hn\xe5\x90\xbb\x19\x04ge\xe0D" +
-	"b\x87\x92\xb3\x8bC\xbe\xc6 4^\x06\x8dH\x1cQ" +
-	"\x1f_\xe6\x90\xdf38F\x9b\xbd\xba\xa9\xc5\xb0\x09\x06" +
-	"\xc2N\xf7\xf1\x0fvM|\xea\xf0\xa5<\xdb3r\xd9" +
-	"l\xba\xae\xd6\x95\"@\x88\xa6\x92\xe9\xa4\x0d\x1f1\xf8" +
-	"\x08\x8e\xd9nf\xec\xbaZKI\x11\"\x97be\x18" +
-	"\x1a\xaaJ\xe6\xa7k\xde\x8a\x8c\xd9\xfb\xcb?U\xd2o" +
-	"\xb5Q\xf6\xc5\xaet\xbb\x9b\xe2T7\xd2\x03\x9e\xa9b" +
-	"\xca\xc7!\xcb\x18\xa2\xedF\xaa\xcdD)\x06$\x84\xd2" +
-	"!\x84U}\xd3\xed\x0ez\xca\xb6n\xbd\xcb\xa7M{" +
-	"u\xb6\xa5\xb1\x91\xd7\xd5&\x00\xf8\x89\x0d\xcf\xf5\xf42" +
-	")& \x97\xefSw\x16\x1e\xf9\"\x93\xa2\xae=\xc2" +
-	"\xce;\xdf\x1e>:\xfdR\xfc\xaa\xf7\xe4\xbc4F\xdc" +
-	"n\x1aD\x09\xf7-\xabY\xe5\xed\x04\xf0F\xafX\xb7" +
-	"D\xb4E\x09b\x9b\x9aT\xdeN\x01o/\x11\x9bf" +
-	"\x8aM\x0a\xee\xd6\xc1\x0a\x13\x16\xder\":\xb7\x8b\x1d" +
-	"\x0a~Q\x07/\xac\x15\xf0\xf6\x1d\xb1\xb7^\x1cP\xf0" +
-	"1\xdd\xf1\xb8 \x9e\xcdH\x0d\xac\x7fMP\xf7\xb0&" +
-	"\x00\xf8\x9d\xde\xdax\xf4\xea\x89qG\x89P\x8d'\xf2" +
-	"=s0\xe3\xd0\x97_\xef\x1b\xd3[\xb1\xc75v\xbc" +
-	"\xc1\x00o2\x10\x0dr\xf0\xcf\xadKW]N\xb5^" +
-	"w\x8fyo\x9e\x065w~\x0c\xec\xba\xb9cgh" +
-	"\xbf\x1b'\x01\x0c\xe7\xf4\xf2:\xcd\xd0ny\xbe\xf5\xfd" +
-	"\x1d\x00\x00\xff\xff\xb0\xce:\xf3"
+const schema_ee8fadeb6a9300eb = "x\xda\xacV[l\x14U\x18\xfe\xbf3Sf\xbbt" +
+	"\x97=\xdd\x12\xc5\x84\xae\xa2\x89\xad\xb1-eC\xb0\xf0" +
+	"@\x0b\x1b\xb0%\xc4=\x14H @3\xb4SX\xba" +
+	"\x17\xd8\x99v!\x80\\\x84h\xb1\\*\x05\x84\xc8\x03" +
+	"(\xde\xb0\x89Hb\x0c\x12\x85\x00\x12L4\x01\xf5\x01" +
+	"\x13\x14\x1f0$\xa0\x06\x8d1Q`\xcc\x99\xeel\xb7" +
+	"j#M\xfb\xb2\xc9\xce\xf7\x9f\xff\xf2\xfd\xd7\x89\x19\xb5" +
+	"V\xad\xf6\xcd\x0e\x12\x13\xd7\x0bF\xd9WN\xcee?" +
+	"\xee:\xb4\x85x\x99r\xef\xd6\xdeU\xb7zw\xffL" +
+	"\x14@\xb8\xb3\xf0\x14\x82G\x0a5\xa2\xe0\xe1\xc2\x17\x83" +
+	"\xe5^-X\xee\x1dco}.\xba\x8ce\xc6w\x11" +
+	"\xaf\x01\x91\xaa\x11\x85\xc7{\xe71R\xed\xc7\x8a\x95\x13" +
+	"\x1f\xbdS\xb1'\x0f\x19\xe7m\x90\x88~N\xbb\xb0t" +
+	"\xd2\x84\xfd}H\x01$4\xd6\xfb\x0a#\x04'{\xa7" +
+	"\x13\xec\xde\xaa\xc9\xa7\x96\x8c{\xed@V\x80I\x81N" +
+	"\xef\x0c)p\xc4\x9b!\xd8\xdf_\xbas\xb7g\xfe\xec" +
+	"7\xf3t_\x18\xbdX\xea\x9e\xf2\xc1\xc2x\xfc\xeb\xc7" +
+	"\xcf\x93\xa8\x81|\xeb`gG70B\xf8\xda\xe8\x10" +
+	"\x08\xf6\xa37+\"\x8d\x8b\x0e_\">\xa5\xcf|\x00" +
+	"\xe1\x1a\xdf\x04\xa9]\xf8\xa4y\xf1\xea9\xbd\xf8\xe2\xfa" +
+	"\xaf\xfa\x04T\x89\xaf\x91\xb8j\xcf\xdc\xfc\xfc\xeb\x97\xf7" +
+	",\xb8A\xdde\x05,G\x0f\xc21\xdf!\x04\xb7\xf8" +
+	"4\xa2\xc6\x0d>E\x1a\xd9\x9ah\xf4\xdcoj\xfa-" +
+	"?\xc6\x84o\xb94\xd2\xe9\x18)=8{\xda\xcb\x91" +
+	"\x89\x7f\x0c \x1a\xe1^\xdf\xe7\x08^\xf29\x11\xf94" +
+	"\x04\x7f\xf7kD\xb6\xb9t\xd9\x8d\x9e9mv\xbe\xb6" +
+	"k\xfe\x1dR\xdb=\xbf\xd4\xf6\xde\xdaq\xeb+\xbb\xfe" +
+	"\xb4\xf3\\\x9e;\xe6\x11F6]\xb1W\xa7SV\xaa" +
+	"\xaa\xd5(h1\xd2\xba\x15K%\xab:\xaa\xabZ\x0d" +
+	"\xf7_eGue\xb3\xbe:\xb9zj\xa3\x956\xf4" +
+	"\xc4L}z<\xbe\\on\x8b\x02\xc2\xa3\x14\x10\xe5" +
+	"\x18C\xf2\xc4\x99L\xf8P\xd3A^=\x89\xa8\xeei" +
+	"\x10\xf1\x1a\x0d\xfd\xe6\xe1R\xc7+\x9e\"\xaa+C\xdd" +
+	"3\xe0\xf5Z(\x93\x8eY\x06GH\xa8\x0c\xfd%&" +
+	"\x13T\x8b1-\xa9\xe4\x7fc\x83<\x88\x02u*8" +
+	"\x8a1?\x17\x9b\xe7\xffb\x9b\xab[\xe9\xd8\xdaY\xfd" +
+	"\x88\x8c\xb05\x16\x8f?1\xcf0\xdb\xe3\x16\xcca\xe8" +
+	"ZaXs\x8cufV\x95I\x94\xd3U8d]" +
+	"\xa6\x91l\x99\x9f\xd6\x93\xa6\xde,\xff\x9b9\xff\x84\xaa" +
+	"\xa8D\xaa\xe4\xc5\xd7@$\x8a\x14\x88g\x19\xecf\xbd" +
+	"/]D\x04\xde\xcf\x95d\xea_\x04JN\x1f\x12*" +
+	"\x00D\x15 `/\xfa\"s~V\xed\xe9mD\x00" +
+	"'\x8c\x00\x07Q=\xad'L\x12\x9e\x9c\xb7\xe5\xcb\x89" +
+	"D\x99\x02q\x9e\x81\x03%Nj\xcf\xca\x10\xce(\x10" +
+	"w\x19l\xd3Hw\x18\xe9\xa66\xd2\x8cu&\x02v" +
+	"\xf0\xe2\xa7\xc7\xf6\x96f\xde\x1d\x10D\xdeG\x8e'\x85" +
+	"G\x06!<\x8a\xfc\x0d(@\x11\xb1a\xbd\xac\x8b\xde" +
+	"n\x99\xb3\xe8\xb3\x8f%\x13\x01\x1a&\xad=\xd5o\xad" +
+	"b=\xa7\xbbG\x84\xd6\x85F\xda\x8c\xa5\x92\x0e\xb3J" +
+	"\xc2\x1c\x89\x9a\xcf&\x89DI.K\x1b\xe7\x11\x89\x0d" +
+	"\x0a\xc4KyY\xda>\x95HlV \xba\x18\xc0J" +
+	"\xc0\x88x\xe7$\"\xb1M\x81\xd8\xc3\xc0\x15V\x02\x85" +
+	"\x88\xef\x94\xe9\xecR \xdef\xe0\xaaR\x02\x95\x88\x1f" +
+	"\x93\x1f\xdfP \xae3\xd8z\xbb\xb5\xb2\xa9E\xb7\x08" +
+	":\x02v\xf7\x89Ov\x97\xbfp\xf4j\x96\xed\xe9\xe9" +
+	"T*Q\x1fqRQD\x08\xc5c\x89\x98\x05\x0f1" +
+	"x\x08\xb6\xd1a$\xad\xfa\x88)S\xe1'\x87b)" +
+	"\xe8\x1fn\x96\x8c/W\xbd\x1f\x1c\xbb\xef\xd7\x7ffI" +
+	"{\xd0A\xd9g\xbb\xd2\x99n\x92SMO\x0chS" +
+	"\xc9\x94G\x81(a\x08u\xe8\xf1v\x03\xc5\x18\xe0\x10" +
+	"\x8a\x87aV\xceMg:hq\xcb|\xf0)\x9f0" +
+	"\xac\x95\xa9\x96\x05\x0b\x94\xfaH\x14@!\xb1\x91)O" +
+	"\xd7\x93|\x02\xd2\xd99\xf5p\xae\xc9\x17\x1a\x14r\xe4" +
+	"\x11\xb0?\xfc\xee\xe8\xf1iW\xc37\xdd\x96s\xdd\x18" +
+	"5T7\x88\xa2N/\xcb]\xe5\x9e\x06p70_" +
+	"\xb3\x98\xb7\x87\x08\xbcSn*\xf7\xb4\x80{\x9e\xf0\x8d" +
+	"3\xf8F\x09wk`\xb9E\x0b\xf7F\xe1\xdbw\xf0" +
+	"\x9d\x12>\xa2A\xc9]\x17p\xcf\x1e\xbe\xaf\x81\x1f\x94" +
+	"p\xaff\xbb\\\x90\x92J\x0a\x15\xac\xffZ\x90uX" +
+	"W\x04\x14\xda\x97#\xe1\xd0\xcd\x93\xa5\xc7\x89P\x8bM" +
+	"\xd9\x999\x98\xb0\xff\x9bo\xf7\x8f\xbd\\\xb1\xd7\x11\xb6" +
+	"\xdd\xc5\x00w3\x10\x0d\xf2\xf0\xafmKV\xfc\x10o" +
+	"\xbb\xed<s{\x9e\x06\x15\xb7\x7f*\xda}\x7f\xe7." +
+	"\xff\x01\xc7N\x14\x18\xc9\xed\x95\x9b4yU1#\xdb" +
+	"\x16\x11\x86M\xe9\xbe\x959\xf4\xe6\xbd\xd3z\xe1~\xe9" +
+	"\x8e\x8e_\x86\xdb\xbcN\x17eG\xeb\xdf\x01\x00\x00\xff" +
+	"\xff\xba%Ur"
 
 func RegisterSchema(reg *schemas.Registry) {
 	reg.Register(&schemas.Schema{
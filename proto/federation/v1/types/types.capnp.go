@@ -21,10 +21,11 @@ const (
 	JsonValue_Which_array   JsonValue_Which = 4
 	JsonValue_Which_object  JsonValue_Which = 5
 	JsonValue_Which_call    JsonValue_Which = 6
+	JsonValue_Which_integer JsonValue_Which = 7
 )
 
 func (w JsonValue_Which) String() string {
-	const s = "nullbooleannumberstring_arrayobjectcall"
+	const s = "nullbooleannumberstring_arrayobjectcallinteger"
 	switch w {
 	case JsonValue_Which_null:
 		return s[0:4]
@@ -40,6 +41,8 @@ func (w JsonValue_Which) String() string {
 		return s[29:35]
 	case JsonValue_Which_call:
 		return s[35:39]
+	case JsonValue_Which_integer:
+		return s[39:46]
 
 	}
 	return "JsonValue_Which(" + strconv.FormatUint(uint64(w), 10) + ")"
@@ -242,6 +245,18 @@ func (s JsonValue) NewCall() (JsonValue_Call, error) {
 	return ss, err
 }
 
+func (s JsonValue) Integer() int64 {
+	if capnp.Struct(s).Uint16(0) != 7 {
+		panic("Which() != integer")
+	}
+	return int64(capnp.Struct(s).Uint64(8))
+}
+
+func (s JsonValue) SetInteger(v int64) {
+	capnp.Struct(s).SetUint16(0, 7)
+	capnp.Struct(s).SetUint64(8, uint64(v))
+}
+
 // JsonValue_List is a list of JsonValue.
 type JsonValue_List = capnp.StructList[JsonValue]
 
@@ -773,12 +788,12 @@ type AuthData capnp.Struct
 const AuthData_TypeID = 0xd9a283298fbeb191
 
 func NewAuthData(s *capnp.Segment) (AuthData, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 3})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 4})
 	return AuthData(st), err
 }
 
 func NewRootAuthData(s *capnp.Segment) (AuthData, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 3})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 16, PointerCount: 4})
 	return AuthData(st), err
 }
 
@@ -881,13 +896,33 @@ func (s AuthData) NewSignatures(n int32) (Signature_List, error) {
 	err = capnp.Struct(s).SetPtr(2, l.ToPtr())
 	return l, err
 }
+func (s AuthData) OriginServerTS() int64 {
+	return int64(capnp.Struct(s).Uint64(8))
+}
+
+func (s AuthData) SetOriginServerTS(v int64) {
+	capnp.Struct(s).SetUint64(8, uint64(v))
+}
+
+func (s AuthData) Nonce() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(3)
+	return []byte(p.Data()), err
+}
+
+func (s AuthData) HasNonce() bool {
+	return capnp.Struct(s).HasPtr(3)
+}
+
+func (s AuthData) SetNonce(v []byte) error {
+	return capnp.Struct(s).SetData(3, v)
+}
 
 // AuthData_List is a list of AuthData.
 type AuthData_List = capnp.StructList[AuthData]
 
 // NewAuthData creates a new list of AuthData.
 func NewAuthData_List(s *capnp.Segment, sz int32) (AuthData_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 8, PointerCount: 3}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 16, PointerCount: 4}, sz)
 	return capnp.StructList[AuthData](l), err
 }
 
@@ -1963,12 +1998,12 @@ func (w Transaction_PDU_Which) String() string {
 const Transaction_PDU_TypeID = 0xb1beb572e4d306be
 
 func NewTransaction_PDU(s *capnp.Segment) (Transaction_PDU, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 24, PointerCount: 12})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 24, PointerCount: 15})
 	return Transaction_PDU(st), err
 }
 
 func NewRootTransaction_PDU(s *capnp.Segment) (Transaction_PDU, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 24, PointerCount: 12})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 24, PointerCount: 15})
 	return Transaction_PDU(st), err
 }
 
@@ -2291,6 +2326,66 @@ func (s Transaction_PDU_roomVersion1) NewUnsigned() (Transaction_PDU_Unsigned, e
 	return ss, err
 }
 
+func (s Transaction_PDU_roomVersion1) Origin() (string, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion1) HasOrigin() bool {
+	return capnp.Struct(s).HasPtr(12)
+}
+
+func (s Transaction_PDU_roomVersion1) OriginBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion1) SetOrigin(v string) error {
+	return capnp.Struct(s).SetText(12, v)
+}
+
+func (s Transaction_PDU_roomVersion1) Membership() (string, error) {
+	p, err := capnp.Struct(s).Ptr(13)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion1) HasMembership() bool {
+	return capnp.Struct(s).HasPtr(13)
+}
+
+func (s Transaction_PDU_roomVersion1) MembershipBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(13)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion1) SetMembership(v string) error {
+	return capnp.Struct(s).SetText(13, v)
+}
+
+func (s Transaction_PDU_roomVersion1) PrevState() (JsonValue, error) {
+	p, err := capnp.Struct(s).Ptr(14)
+	return JsonValue(p.Struct()), err
+}
+
+func (s Transaction_PDU_roomVersion1) HasPrevState() bool {
+	return capnp.Struct(s).HasPtr(14)
+}
+
+func (s Transaction_PDU_roomVersion1) SetPrevState(v JsonValue) error {
+	return capnp.Struct(s).SetPtr(14, capnp.Struct(v).ToPtr())
+}
+
+// NewPrevState sets the prevState field to a newly
+// allocated JsonValue struct, preferring placement in s's segment.
+func (s Transaction_PDU_roomVersion1) NewPrevState() (JsonValue, error) {
+	ss, err := NewJsonValue(capnp.Struct(s).Segment())
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = capnp.Struct(s).SetPtr(14, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
 func (s Transaction_PDU) RoomVersion2() Transaction_PDU_roomVersion2 {
 	return Transaction_PDU_roomVersion2(s)
 }
@@ -2574,6 +2669,66 @@ func (s Transaction_PDU_roomVersion2) NewUnsigned() (Transaction_PDU_Unsigned, e
 	return ss, err
 }
 
+func (s Transaction_PDU_roomVersion2) Origin() (string, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion2) HasOrigin() bool {
+	return capnp.Struct(s).HasPtr(12)
+}
+
+func (s Transaction_PDU_roomVersion2) OriginBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion2) SetOrigin(v string) error {
+	return capnp.Struct(s).SetText(12, v)
+}
+
+func (s Transaction_PDU_roomVersion2) Membership() (string, error) {
+	p, err := capnp.Struct(s).Ptr(13)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion2) HasMembership() bool {
+	return capnp.Struct(s).HasPtr(13)
+}
+
+func (s Transaction_PDU_roomVersion2) MembershipBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(13)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion2) SetMembership(v string) error {
+	return capnp.Struct(s).SetText(13, v)
+}
+
+func (s Transaction_PDU_roomVersion2) PrevState() (JsonValue, error) {
+	p, err := capnp.Struct(s).Ptr(14)
+	return JsonValue(p.Struct()), err
+}
+
+func (s Transaction_PDU_roomVersion2) HasPrevState() bool {
+	return capnp.Struct(s).HasPtr(14)
+}
+
+func (s Transaction_PDU_roomVersion2) SetPrevState(v JsonValue) error {
+	return capnp.Struct(s).SetPtr(14, capnp.Struct(v).ToPtr())
+}
+
+// NewPrevState sets the prevState field to a newly
+// allocated JsonValue struct, preferring placement in s's segment.
+func (s Transaction_PDU_roomVersion2) NewPrevState() (JsonValue, error) {
+	ss, err := NewJsonValue(capnp.Struct(s).Segment())
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = capnp.Struct(s).SetPtr(14, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
 func (s Transaction_PDU) RoomVersion3() Transaction_PDU_roomVersion3 {
 	return Transaction_PDU_roomVersion3(s)
 }
@@ -2839,6 +2994,66 @@ func (s Transaction_PDU_roomVersion3) NewUnsigned() (Transaction_PDU_Unsigned, e
 	return ss, err
 }
 
+func (s Transaction_PDU_roomVersion3) Origin() (string, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion3) HasOrigin() bool {
+	return capnp.Struct(s).HasPtr(11)
+}
+
+func (s Transaction_PDU_roomVersion3) OriginBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion3) SetOrigin(v string) error {
+	return capnp.Struct(s).SetText(11, v)
+}
+
+func (s Transaction_PDU_roomVersion3) Membership() (string, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion3) HasMembership() bool {
+	return capnp.Struct(s).HasPtr(12)
+}
+
+func (s Transaction_PDU_roomVersion3) MembershipBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion3) SetMembership(v string) error {
+	return capnp.Struct(s).SetText(12, v)
+}
+
+func (s Transaction_PDU_roomVersion3) PrevState() (JsonValue, error) {
+	p, err := capnp.Struct(s).Ptr(13)
+	return JsonValue(p.Struct()), err
+}
+
+func (s Transaction_PDU_roomVersion3) HasPrevState() bool {
+	return capnp.Struct(s).HasPtr(13)
+}
+
+func (s Transaction_PDU_roomVersion3) SetPrevState(v JsonValue) error {
+	return capnp.Struct(s).SetPtr(13, capnp.Struct(v).ToPtr())
+}
+
+// NewPrevState sets the prevState field to a newly
+// allocated JsonValue struct, preferring placement in s's segment.
+func (s Transaction_PDU_roomVersion3) NewPrevState() (JsonValue, error) {
+	ss, err := NewJsonValue(capnp.Struct(s).Segment())
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = capnp.Struct(s).SetPtr(13, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
 func (s Transaction_PDU) RoomVersion4() Transaction_PDU_roomVersion4 {
 	return Transaction_PDU_roomVersion4(s)
 }
@@ -3104,6 +3319,66 @@ func (s Transaction_PDU_roomVersion4) NewUnsigned() (Transaction_PDU_Unsigned, e
 	return ss, err
 }
 
+func (s Transaction_PDU_roomVersion4) Origin() (string, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion4) HasOrigin() bool {
+	return capnp.Struct(s).HasPtr(11)
+}
+
+func (s Transaction_PDU_roomVersion4) OriginBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion4) SetOrigin(v string) error {
+	return capnp.Struct(s).SetText(11, v)
+}
+
+func (s Transaction_PDU_roomVersion4) Membership() (string, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion4) HasMembership() bool {
+	return capnp.Struct(s).HasPtr(12)
+}
+
+func (s Transaction_PDU_roomVersion4) MembershipBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion4) SetMembership(v string) error {
+	return capnp.Struct(s).SetText(12, v)
+}
+
+func (s Transaction_PDU_roomVersion4) PrevState() (JsonValue, error) {
+	p, err := capnp.Struct(s).Ptr(13)
+	return JsonValue(p.Struct()), err
+}
+
+func (s Transaction_PDU_roomVersion4) HasPrevState() bool {
+	return capnp.Struct(s).HasPtr(13)
+}
+
+func (s Transaction_PDU_roomVersion4) SetPrevState(v JsonValue) error {
+	return capnp.Struct(s).SetPtr(13, capnp.Struct(v).ToPtr())
+}
+
+// NewPrevState sets the prevState field to a newly
+// allocated JsonValue struct, preferring placement in s's segment.
+func (s Transaction_PDU_roomVersion4) NewPrevState() (JsonValue, error) {
+	ss, err := NewJsonValue(capnp.Struct(s).Segment())
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = capnp.Struct(s).SetPtr(13, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
 func (s Transaction_PDU) RoomVersion5() Transaction_PDU_roomVersion5 {
 	return Transaction_PDU_roomVersion5(s)
 }
@@ -3369,6 +3644,66 @@ func (s Transaction_PDU_roomVersion5) NewUnsigned() (Transaction_PDU_Unsigned, e
 	return ss, err
 }
 
+func (s Transaction_PDU_roomVersion5) Origin() (string, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion5) HasOrigin() bool {
+	return capnp.Struct(s).HasPtr(11)
+}
+
+func (s Transaction_PDU_roomVersion5) OriginBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion5) SetOrigin(v string) error {
+	return capnp.Struct(s).SetText(11, v)
+}
+
+func (s Transaction_PDU_roomVersion5) Membership() (string, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion5) HasMembership() bool {
+	return capnp.Struct(s).HasPtr(12)
+}
+
+func (s Transaction_PDU_roomVersion5) MembershipBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion5) SetMembership(v string) error {
+	return capnp.Struct(s).SetText(12, v)
+}
+
+func (s Transaction_PDU_roomVersion5) PrevState() (JsonValue, error) {
+	p, err := capnp.Struct(s).Ptr(13)
+	return JsonValue(p.Struct()), err
+}
+
+func (s Transaction_PDU_roomVersion5) HasPrevState() bool {
+	return capnp.Struct(s).HasPtr(13)
+}
+
+func (s Transaction_PDU_roomVersion5) SetPrevState(v JsonValue) error {
+	return capnp.Struct(s).SetPtr(13, capnp.Struct(v).ToPtr())
+}
+
+// NewPrevState sets the prevState field to a newly
+// allocated JsonValue struct, preferring placement in s's segment.
+func (s Transaction_PDU_roomVersion5) NewPrevState() (JsonValue, error) {
+	ss, err := NewJsonValue(capnp.Struct(s).Segment())
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = capnp.Struct(s).SetPtr(13, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
 func (s Transaction_PDU) RoomVersion6() Transaction_PDU_roomVersion6 {
 	return Transaction_PDU_roomVersion6(s)
 }
@@ -3634,6 +3969,66 @@ func (s Transaction_PDU_roomVersion6) NewUnsigned() (Transaction_PDU_Unsigned, e
 	return ss, err
 }
 
+func (s Transaction_PDU_roomVersion6) Origin() (string, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion6) HasOrigin() bool {
+	return capnp.Struct(s).HasPtr(11)
+}
+
+func (s Transaction_PDU_roomVersion6) OriginBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion6) SetOrigin(v string) error {
+	return capnp.Struct(s).SetText(11, v)
+}
+
+func (s Transaction_PDU_roomVersion6) Membership() (string, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion6) HasMembership() bool {
+	return capnp.Struct(s).HasPtr(12)
+}
+
+func (s Transaction_PDU_roomVersion6) MembershipBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion6) SetMembership(v string) error {
+	return capnp.Struct(s).SetText(12, v)
+}
+
+func (s Transaction_PDU_roomVersion6) PrevState() (JsonValue, error) {
+	p, err := capnp.Struct(s).Ptr(13)
+	return JsonValue(p.Struct()), err
+}
+
+func (s Transaction_PDU_roomVersion6) HasPrevState() bool {
+	return capnp.Struct(s).HasPtr(13)
+}
+
+func (s Transaction_PDU_roomVersion6) SetPrevState(v JsonValue) error {
+	return capnp.Struct(s).SetPtr(13, capnp.Struct(v).ToPtr())
+}
+
+// NewPrevState sets the prevState field to a newly
+// allocated JsonValue struct, preferring placement in s's segment.
+func (s Transaction_PDU_roomVersion6) NewPrevState() (JsonValue, error) {
+	ss, err := NewJsonValue(capnp.Struct(s).Segment())
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = capnp.Struct(s).SetPtr(13, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
 func (s Transaction_PDU) RoomVersion7() Transaction_PDU_roomVersion7 {
 	return Transaction_PDU_roomVersion7(s)
 }
@@ -3899,20 +4294,80 @@ func (s Transaction_PDU_roomVersion7) NewUnsigned() (Transaction_PDU_Unsigned, e
 	return ss, err
 }
 
-func (s Transaction_PDU) RoomVersion8() Transaction_PDU_roomVersion8 {
-	return Transaction_PDU_roomVersion8(s)
+func (s Transaction_PDU_roomVersion7) Origin() (string, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.Text(), err
 }
 
-func (s Transaction_PDU) SetRoomVersion8() {
-	capnp.Struct(s).SetUint16(16, 7)
+func (s Transaction_PDU_roomVersion7) HasOrigin() bool {
+	return capnp.Struct(s).HasPtr(11)
 }
 
-func (s Transaction_PDU_roomVersion8) IsValid() bool {
-	return capnp.Struct(s).IsValid()
+func (s Transaction_PDU_roomVersion7) OriginBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.TextBytes(), err
 }
 
-func (s Transaction_PDU_roomVersion8) Message() *capnp.Message {
-	return capnp.Struct(s).Message()
+func (s Transaction_PDU_roomVersion7) SetOrigin(v string) error {
+	return capnp.Struct(s).SetText(11, v)
+}
+
+func (s Transaction_PDU_roomVersion7) Membership() (string, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion7) HasMembership() bool {
+	return capnp.Struct(s).HasPtr(12)
+}
+
+func (s Transaction_PDU_roomVersion7) MembershipBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion7) SetMembership(v string) error {
+	return capnp.Struct(s).SetText(12, v)
+}
+
+func (s Transaction_PDU_roomVersion7) PrevState() (JsonValue, error) {
+	p, err := capnp.Struct(s).Ptr(13)
+	return JsonValue(p.Struct()), err
+}
+
+func (s Transaction_PDU_roomVersion7) HasPrevState() bool {
+	return capnp.Struct(s).HasPtr(13)
+}
+
+func (s Transaction_PDU_roomVersion7) SetPrevState(v JsonValue) error {
+	return capnp.Struct(s).SetPtr(13, capnp.Struct(v).ToPtr())
+}
+
+// NewPrevState sets the prevState field to a newly
+// allocated JsonValue struct, preferring placement in s's segment.
+func (s Transaction_PDU_roomVersion7) NewPrevState() (JsonValue, error) {
+	ss, err := NewJsonValue(capnp.Struct(s).Segment())
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = capnp.Struct(s).SetPtr(13, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
+func (s Transaction_PDU) RoomVersion8() Transaction_PDU_roomVersion8 {
+	return Transaction_PDU_roomVersion8(s)
+}
+
+func (s Transaction_PDU) SetRoomVersion8() {
+	capnp.Struct(s).SetUint16(16, 7)
+}
+
+func (s Transaction_PDU_roomVersion8) IsValid() bool {
+	return capnp.Struct(s).IsValid()
+}
+
+func (s Transaction_PDU_roomVersion8) Message() *capnp.Message {
+	return capnp.Struct(s).Message()
 }
 
 func (s Transaction_PDU_roomVersion8) Segment() *capnp.Segment {
@@ -4164,6 +4619,66 @@ func (s Transaction_PDU_roomVersion8) NewUnsigned() (Transaction_PDU_Unsigned, e
 	return ss, err
 }
 
+func (s Transaction_PDU_roomVersion8) Origin() (string, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion8) HasOrigin() bool {
+	return capnp.Struct(s).HasPtr(11)
+}
+
+func (s Transaction_PDU_roomVersion8) OriginBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion8) SetOrigin(v string) error {
+	return capnp.Struct(s).SetText(11, v)
+}
+
+func (s Transaction_PDU_roomVersion8) Membership() (string, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion8) HasMembership() bool {
+	return capnp.Struct(s).HasPtr(12)
+}
+
+func (s Transaction_PDU_roomVersion8) MembershipBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion8) SetMembership(v string) error {
+	return capnp.Struct(s).SetText(12, v)
+}
+
+func (s Transaction_PDU_roomVersion8) PrevState() (JsonValue, error) {
+	p, err := capnp.Struct(s).Ptr(13)
+	return JsonValue(p.Struct()), err
+}
+
+func (s Transaction_PDU_roomVersion8) HasPrevState() bool {
+	return capnp.Struct(s).HasPtr(13)
+}
+
+func (s Transaction_PDU_roomVersion8) SetPrevState(v JsonValue) error {
+	return capnp.Struct(s).SetPtr(13, capnp.Struct(v).ToPtr())
+}
+
+// NewPrevState sets the prevState field to a newly
+// allocated JsonValue struct, preferring placement in s's segment.
+func (s Transaction_PDU_roomVersion8) NewPrevState() (JsonValue, error) {
+	ss, err := NewJsonValue(capnp.Struct(s).Segment())
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = capnp.Struct(s).SetPtr(13, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
 func (s Transaction_PDU) RoomVersion9() Transaction_PDU_roomVersion9 {
 	return Transaction_PDU_roomVersion9(s)
 }
@@ -4429,6 +4944,66 @@ func (s Transaction_PDU_roomVersion9) NewUnsigned() (Transaction_PDU_Unsigned, e
 	return ss, err
 }
 
+func (s Transaction_PDU_roomVersion9) Origin() (string, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion9) HasOrigin() bool {
+	return capnp.Struct(s).HasPtr(11)
+}
+
+func (s Transaction_PDU_roomVersion9) OriginBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion9) SetOrigin(v string) error {
+	return capnp.Struct(s).SetText(11, v)
+}
+
+func (s Transaction_PDU_roomVersion9) Membership() (string, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion9) HasMembership() bool {
+	return capnp.Struct(s).HasPtr(12)
+}
+
+func (s Transaction_PDU_roomVersion9) MembershipBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion9) SetMembership(v string) error {
+	return capnp.Struct(s).SetText(12, v)
+}
+
+func (s Transaction_PDU_roomVersion9) PrevState() (JsonValue, error) {
+	p, err := capnp.Struct(s).Ptr(13)
+	return JsonValue(p.Struct()), err
+}
+
+func (s Transaction_PDU_roomVersion9) HasPrevState() bool {
+	return capnp.Struct(s).HasPtr(13)
+}
+
+func (s Transaction_PDU_roomVersion9) SetPrevState(v JsonValue) error {
+	return capnp.Struct(s).SetPtr(13, capnp.Struct(v).ToPtr())
+}
+
+// NewPrevState sets the prevState field to a newly
+// allocated JsonValue struct, preferring placement in s's segment.
+func (s Transaction_PDU_roomVersion9) NewPrevState() (JsonValue, error) {
+	ss, err := NewJsonValue(capnp.Struct(s).Segment())
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = capnp.Struct(s).SetPtr(13, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
 func (s Transaction_PDU) RoomVersion10() Transaction_PDU_roomVersion10 {
 	return Transaction_PDU_roomVersion10(s)
 }
@@ -4694,6 +5269,66 @@ func (s Transaction_PDU_roomVersion10) NewUnsigned() (Transaction_PDU_Unsigned,
 	return ss, err
 }
 
+func (s Transaction_PDU_roomVersion10) Origin() (string, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion10) HasOrigin() bool {
+	return capnp.Struct(s).HasPtr(11)
+}
+
+func (s Transaction_PDU_roomVersion10) OriginBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion10) SetOrigin(v string) error {
+	return capnp.Struct(s).SetText(11, v)
+}
+
+func (s Transaction_PDU_roomVersion10) Membership() (string, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion10) HasMembership() bool {
+	return capnp.Struct(s).HasPtr(12)
+}
+
+func (s Transaction_PDU_roomVersion10) MembershipBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion10) SetMembership(v string) error {
+	return capnp.Struct(s).SetText(12, v)
+}
+
+func (s Transaction_PDU_roomVersion10) PrevState() (JsonValue, error) {
+	p, err := capnp.Struct(s).Ptr(13)
+	return JsonValue(p.Struct()), err
+}
+
+func (s Transaction_PDU_roomVersion10) HasPrevState() bool {
+	return capnp.Struct(s).HasPtr(13)
+}
+
+func (s Transaction_PDU_roomVersion10) SetPrevState(v JsonValue) error {
+	return capnp.Struct(s).SetPtr(13, capnp.Struct(v).ToPtr())
+}
+
+// NewPrevState sets the prevState field to a newly
+// allocated JsonValue struct, preferring placement in s's segment.
+func (s Transaction_PDU_roomVersion10) NewPrevState() (JsonValue, error) {
+	ss, err := NewJsonValue(capnp.Struct(s).Segment())
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = capnp.Struct(s).SetPtr(13, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
 func (s Transaction_PDU) RoomVersion11() Transaction_PDU_roomVersion11 {
 	return Transaction_PDU_roomVersion11(s)
 }
@@ -4941,12 +5576,72 @@ func (s Transaction_PDU_roomVersion11) NewUnsigned() (Transaction_PDU_Unsigned,
 	return ss, err
 }
 
+func (s Transaction_PDU_roomVersion11) Origin() (string, error) {
+	p, err := capnp.Struct(s).Ptr(10)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion11) HasOrigin() bool {
+	return capnp.Struct(s).HasPtr(10)
+}
+
+func (s Transaction_PDU_roomVersion11) OriginBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(10)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion11) SetOrigin(v string) error {
+	return capnp.Struct(s).SetText(10, v)
+}
+
+func (s Transaction_PDU_roomVersion11) Membership() (string, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.Text(), err
+}
+
+func (s Transaction_PDU_roomVersion11) HasMembership() bool {
+	return capnp.Struct(s).HasPtr(11)
+}
+
+func (s Transaction_PDU_roomVersion11) MembershipBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(11)
+	return p.TextBytes(), err
+}
+
+func (s Transaction_PDU_roomVersion11) SetMembership(v string) error {
+	return capnp.Struct(s).SetText(11, v)
+}
+
+func (s Transaction_PDU_roomVersion11) PrevState() (JsonValue, error) {
+	p, err := capnp.Struct(s).Ptr(12)
+	return JsonValue(p.Struct()), err
+}
+
+func (s Transaction_PDU_roomVersion11) HasPrevState() bool {
+	return capnp.Struct(s).HasPtr(12)
+}
+
+func (s Transaction_PDU_roomVersion11) SetPrevState(v JsonValue) error {
+	return capnp.Struct(s).SetPtr(12, capnp.Struct(v).ToPtr())
+}
+
+// NewPrevState sets the prevState field to a newly
+// allocated JsonValue struct, preferring placement in s's segment.
+func (s Transaction_PDU_roomVersion11) NewPrevState() (JsonValue, error) {
+	ss, err := NewJsonValue(capnp.Struct(s).Segment())
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = capnp.Struct(s).SetPtr(12, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
 // Transaction_PDU_List is a list of Transaction_PDU.
 type Transaction_PDU_List = capnp.StructList[Transaction_PDU]
 
 // NewTransaction_PDU creates a new list of Transaction_PDU.
 func NewTransaction_PDU_List(s *capnp.Segment, sz int32) (Transaction_PDU_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 24, PointerCount: 12}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 24, PointerCount: 15}, sz)
 	return capnp.StructList[Transaction_PDU](l), err
 }
 
@@ -4974,6 +5669,9 @@ func (p Transaction_PDU_roomVersion1_Future) Content() JsonValue_Future {
 func (p Transaction_PDU_roomVersion1_Future) Unsigned() Transaction_PDU_Unsigned_Future {
 	return Transaction_PDU_Unsigned_Future{Future: p.Future.Field(11, nil)}
 }
+func (p Transaction_PDU_roomVersion1_Future) PrevState() JsonValue_Future {
+	return JsonValue_Future{Future: p.Future.Field(14, nil)}
+}
 func (p Transaction_PDU_Future) RoomVersion2() Transaction_PDU_roomVersion2_Future {
 	return Transaction_PDU_roomVersion2_Future{p.Future}
 }
@@ -4991,6 +5689,9 @@ func (p Transaction_PDU_roomVersion2_Future) Content() JsonValue_Future {
 func (p Transaction_PDU_roomVersion2_Future) Unsigned() Transaction_PDU_Unsigned_Future {
 	return Transaction_PDU_Unsigned_Future{Future: p.Future.Field(11, nil)}
 }
+func (p Transaction_PDU_roomVersion2_Future) PrevState() JsonValue_Future {
+	return JsonValue_Future{Future: p.Future.Field(14, nil)}
+}
 func (p Transaction_PDU_Future) RoomVersion3() Transaction_PDU_roomVersion3_Future {
 	return Transaction_PDU_roomVersion3_Future{p.Future}
 }
@@ -5008,6 +5709,9 @@ func (p Transaction_PDU_roomVersion3_Future) Content() JsonValue_Future {
 func (p Transaction_PDU_roomVersion3_Future) Unsigned() Transaction_PDU_Unsigned_Future {
 	return Transaction_PDU_Unsigned_Future{Future: p.Future.Field(10, nil)}
 }
+func (p Transaction_PDU_roomVersion3_Future) PrevState() JsonValue_Future {
+	return JsonValue_Future{Future: p.Future.Field(13, nil)}
+}
 func (p Transaction_PDU_Future) RoomVersion4() Transaction_PDU_roomVersion4_Future {
 	return Transaction_PDU_roomVersion4_Future{p.Future}
 }
@@ -5025,6 +5729,9 @@ func (p Transaction_PDU_roomVersion4_Future) Content() JsonValue_Future {
 func (p Transaction_PDU_roomVersion4_Future) Unsigned() Transaction_PDU_Unsigned_Future {
 	return Transaction_PDU_Unsigned_Future{Future: p.Future.Field(10, nil)}
 }
+func (p Transaction_PDU_roomVersion4_Future) PrevState() JsonValue_Future {
+	return JsonValue_Future{Future: p.Future.Field(13, nil)}
+}
 func (p Transaction_PDU_Future) RoomVersion5() Transaction_PDU_roomVersion5_Future {
 	return Transaction_PDU_roomVersion5_Future{p.Future}
 }
@@ -5042,6 +5749,9 @@ func (p Transaction_PDU_roomVersion5_Future) Content() JsonValue_Future {
 func (p Transaction_PDU_roomVersion5_Future) Unsigned() Transaction_PDU_Unsigned_Future {
 	return Transaction_PDU_Unsigned_Future{Future: p.Future.Field(10, nil)}
 }
+func (p Transaction_PDU_roomVersion5_Future) PrevState() JsonValue_Future {
+	return JsonValue_Future{Future: p.Future.Field(13, nil)}
+}
 func (p Transaction_PDU_Future) RoomVersion6() Transaction_PDU_roomVersion6_Future {
 	return Transaction_PDU_roomVersion6_Future{p.Future}
 }
@@ -5059,6 +5769,9 @@ func (p Transaction_PDU_roomVersion6_Future) Content() JsonValue_Future {
 func (p Transaction_PDU_roomVersion6_Future) Unsigned() Transaction_PDU_Unsigned_Future {
 	return Transaction_PDU_Unsigned_Future{Future: p.Future.Field(10, nil)}
 }
+func (p Transaction_PDU_roomVersion6_Future) PrevState() JsonValue_Future {
+	return JsonValue_Future{Future: p.Future.Field(13, nil)}
+}
 func (p Transaction_PDU_Future) RoomVersion7() Transaction_PDU_roomVersion7_Future {
 	return Transaction_PDU_roomVersion7_Future{p.Future}
 }
@@ -5076,6 +5789,9 @@ func (p Transaction_PDU_roomVersion7_Future) Content() JsonValue_Future {
 func (p Transaction_PDU_roomVersion7_Future) Unsigned() Transaction_PDU_Unsigned_Future {
 	return Transaction_PDU_Unsigned_Future{Future: p.Future.Field(10, nil)}
 }
+func (p Transaction_PDU_roomVersion7_Future) PrevState() JsonValue_Future {
+	return JsonValue_Future{Future: p.Future.Field(13, nil)}
+}
 func (p Transaction_PDU_Future) RoomVersion8() Transaction_PDU_roomVersion8_Future {
 	return Transaction_PDU_roomVersion8_Future{p.Future}
 }
@@ -5093,6 +5809,9 @@ func (p Transaction_PDU_roomVersion8_Future) Content() JsonValue_Future {
 func (p Transaction_PDU_roomVersion8_Future) Unsigned() Transaction_PDU_Unsigned_Future {
 	return Transaction_PDU_Unsigned_Future{Future: p.Future.Field(10, nil)}
 }
+func (p Transaction_PDU_roomVersion8_Future) PrevState() JsonValue_Future {
+	return JsonValue_Future{Future: p.Future.Field(13, nil)}
+}
 func (p Transaction_PDU_Future) RoomVersion9() Transaction_PDU_roomVersion9_Future {
 	return Transaction_PDU_roomVersion9_Future{p.Future}
 }
@@ -5110,6 +5829,9 @@ func (p Transaction_PDU_roomVersion9_Future) Content() JsonValue_Future {
 func (p Transaction_PDU_roomVersion9_Future) Unsigned() Transaction_PDU_Unsigned_Future {
 	return Transaction_PDU_Unsigned_Future{Future: p.Future.Field(10, nil)}
 }
+func (p Transaction_PDU_roomVersion9_Future) PrevState() JsonValue_Future {
+	return JsonValue_Future{Future: p.Future.Field(13, nil)}
+}
 func (p Transaction_PDU_Future) RoomVersion10() Transaction_PDU_roomVersion10_Future {
 	return Transaction_PDU_roomVersion10_Future{p.Future}
 }
@@ -5127,6 +5849,9 @@ func (p Transaction_PDU_roomVersion10_Future) Content() JsonValue_Future {
 func (p Transaction_PDU_roomVersion10_Future) Unsigned() Transaction_PDU_Unsigned_Future {
 	return Transaction_PDU_Unsigned_Future{Future: p.Future.Field(10, nil)}
 }
+func (p Transaction_PDU_roomVersion10_Future) PrevState() JsonValue_Future {
+	return JsonValue_Future{Future: p.Future.Field(13, nil)}
+}
 func (p Transaction_PDU_Future) RoomVersion11() Transaction_PDU_roomVersion11_Future {
 	return Transaction_PDU_roomVersion11_Future{p.Future}
 }
@@ -5144,6 +5869,9 @@ func (p Transaction_PDU_roomVersion11_Future) Content() JsonValue_Future {
 func (p Transaction_PDU_roomVersion11_Future) Unsigned() Transaction_PDU_Unsigned_Future {
 	return Transaction_PDU_Unsigned_Future{Future: p.Future.Field(9, nil)}
 }
+func (p Transaction_PDU_roomVersion11_Future) PrevState() JsonValue_Future {
+	return JsonValue_Future{Future: p.Future.Field(12, nil)}
+}
 
 type Transaction_PDU_EventReference capnp.Struct
 type Transaction_PDU_EventReference_Which uint16
@@ -5287,12 +6015,12 @@ type Transaction_PDU_Unsigned capnp.Struct
 const Transaction_PDU_Unsigned_TypeID = 0x83ba1e25907bb64e
 
 func NewTransaction_PDU_Unsigned(s *capnp.Segment) (Transaction_PDU_Unsigned, error) {
-	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 0})
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 1})
 	return Transaction_PDU_Unsigned(st), err
 }
 
 func NewRootTransaction_PDU_Unsigned(s *capnp.Segment) (Transaction_PDU_Unsigned, error) {
-	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 0})
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 1})
 	return Transaction_PDU_Unsigned(st), err
 }
 
@@ -5336,12 +6064,36 @@ func (s Transaction_PDU_Unsigned) SetAge(v uint64) {
 	capnp.Struct(s).SetUint64(0, v)
 }
 
+func (s Transaction_PDU_Unsigned) Object() (JsonValue, error) {
+	p, err := capnp.Struct(s).Ptr(0)
+	return JsonValue(p.Struct()), err
+}
+
+func (s Transaction_PDU_Unsigned) HasObject() bool {
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s Transaction_PDU_Unsigned) SetObject(v JsonValue) error {
+	return capnp.Struct(s).SetPtr(0, capnp.Struct(v).ToPtr())
+}
+
+// NewObject sets the object field to a newly
+// allocated JsonValue struct, preferring placement in s's segment.
+func (s Transaction_PDU_Unsigned) NewObject() (JsonValue, error) {
+	ss, err := NewJsonValue(capnp.Struct(s).Segment())
+	if err != nil {
+		return JsonValue{}, err
+	}
+	err = capnp.Struct(s).SetPtr(0, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
 // Transaction_PDU_Unsigned_List is a list of Transaction_PDU_Unsigned.
 type Transaction_PDU_Unsigned_List = capnp.StructList[Transaction_PDU_Unsigned]
 
 // NewTransaction_PDU_Unsigned creates a new list of Transaction_PDU_Unsigned.
 func NewTransaction_PDU_Unsigned_List(s *capnp.Segment, sz int32) (Transaction_PDU_Unsigned_List, error) {
-	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 8, PointerCount: 0}, sz)
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 8, PointerCount: 1}, sz)
 	return capnp.StructList[Transaction_PDU_Unsigned](l), err
 }
 
@@ -5352,6 +6104,9 @@ func (f Transaction_PDU_Unsigned_Future) Struct() (Transaction_PDU_Unsigned, err
 	p, err := f.Future.Ptr()
 	return Transaction_PDU_Unsigned(p.Struct()), err
 }
+func (p Transaction_PDU_Unsigned_Future) Object() JsonValue_Future {
+	return JsonValue_Future{Future: p.Future.Field(0, nil)}
+}
 
 type BackfillData capnp.Struct
 type BackfillData_Which uint16
@@ -5599,223 +6354,507 @@ func (f BackfillData_Metadata_Future) Struct() (BackfillData_Metadata, error) {
 	return BackfillData_Metadata(p.Struct()), err
 }
 
-const schema_b8a1e7de8a3a89ec = "x\xda\xecZ\x7fpT\xd7u>\xe7\xbd]\xad\x16$" +
-	"\xb4\x8f\xb7\x0eu0^#\x0b\x1b)FHZ\x09K" +
-	"\x8a\xd3E\xb2D\xb0\x84\x1c=V\"F\x85\x84\x87\xf6" +
-	"\x81\x16\xaf\xde\xaev\xdf\x0a\x96\xe0\xf0\xbb1\x0e\x04;" +
-	"\xb1[Hp[\x18S\x93\x1f\x9e\x82'xh\x0d\x18" +
-	"&\xd4\xb5;Ik\xa6a\x0am\x86\xc4m\x18C\x87" +
-	"N\x9b\x0cv\x0b\x0dl\xe7\xdc\xdd\xf7\xde\xee\xb2\xb7\x80" +
-	"\x81\xc43\xd5?\x9a\xb9\xdf\x1e\xddw\xee\xb9\xdf9\xdf" +
-	"=\xf7\xbd\xba?\xf2\xccv\xd4\x97\xff\xf1D\x10\x94\xd3" +
-	"\xce\x92\xf4\x93o|\xe5\xf9i\xf7\xff\xd5FPf!" +
-	"\xa6\x8f\x96\xfc\xc3/\xe3\x07\x8f\x1e\x00\x87\x0b\xc0_\xef" +
-	"\xbe\x82r\x8f\xdb\x05 ?\xe1\x0e\x00\xa6\x17\xfed\xe5" +
-	"\x899\xb3\x0fo\x02\xe5!\xc4\xf4\xc5-\xad\xcf\x9d\xfd" +
-	"`\xf7!\xe8\x14\\\"\x80?\xec\xfe&\xca\xeb\xdd\x8f" +
-	"\x02\xc8{\xdd\x1f\x00\xa6wy\x07\xaa\xd5\xaf\xfez\x13" +
-	"\xcd-\xdas\xdfS\xe6B\x00\xff\xfaq\x9f\x16\x00\xfd" +
-	";\xc7\xfd\\\x00L\xb7<\x13|\xaf\xf1_\xe3[A" +
-	"\xaa\xc3\xf476\xec;\xfb\x9d\xdf\xccy\x03\x9c\x02\xf9" +
-	"\xf1~\xf9\xf7P\xbe\\N~|X\xbe\x120\xfdb" +
-	"\xfd\xab+\x84\x17\x0f\xbfP\xdc\x0fm\xc2\xdf\xa2\xbc~" +
-	"\xc2\xc3\x00\xf2\xcb\x13\xc8\x8f\xba]G\xab\x8e\xec\x08\xbd" +
-	"\x04J\x0b\xe6\xfc\xb3\x13i\xf2\xa9\x9e\x06An\xf3\xd0" +
-	"\xe4\x9f\xf3\xd0\xe4\x1f\x9d\xf8\x93K\xfbN\x9f\xdeY\xcc" +
-	"\x93\x97=\xaf\xa3|\x90\x19\x1f`\xc6\x1d\x9f\x97G\x1c" +
-	"\x07\xfe\xfe\xdb\xc5\x97X.\xb1%N\x93\xd8\x12\xab\xfe" +
-	"\xf9[\xa7~\xf6\\\xcd\xae\xe2\xb6\xc3\xdeJ\xb2\xdd\xec" +
-	"e\xb6\x81\x86\xda\x91\xb7\xd7\xf4\xfeiq\xdb\x9fLb" +
-	"\xf3\x9e\x9b\xc4l\xbb[\xde2^\x1d\xb7}7H\x0d" +
-	"9[\x94qx\xc6\xe4\xd7Q\xee\x9cL\x0e\xb7M&" +
-	"\x87\xbf\xe4Xt\xa5\xef\xeb\x97\xf6\x15\x9f\xf8\xe5\xc9l" +
-	"\xe2\x83\x93'\x89\x80i\xf9o\xde\xda\xfb\xad\xfbW~" +
-	"\x0f\xa4\x87r\xa2\xecD\x97\x07\xfd-\xbe\xf9(\xf7\xf8" +
-	"&\x01\xc8\xfd\xbe\x00\xc5\xc37\xe9\xadK\x83W:\xbe" +
-	"y`\xbfT\x93\xf3\xbfN\x81\xcc_\xf3m@\xf9\xb8" +
-	"\x8f\xfc8\xe2#?\xac'+\x0d(\xda^w\x96\xb9" +
-	"\xc6c\xa9\xff\xde\x07^G\xb9\xfe\x81\x87\x01\xfc=\x0f" +
-	"Di\x91\xd6F(\x0f\xa1\x90\xb3\xe5\xe8\xa2e\x1e\x99" +
-	"\xba\x01\xe5\x93S\xc9\xfe\xdc\xd4\xedHAi\xff\xe1\x99" +
-	"\xaa\xb9\xd5o\x16\xf0\xba\x13]\x02\x80\x7f\xdb\x835\x82" +
-	"\xfc\xda\x83\xe4\xcew\x1f$w\xdaz/\x86\xba\x17\xbe" +
-	"\xfdf\x01\xa3X\x1a8\xab\xf6\xa0<\xa5\x8a\x8c\xef\xad" +
-	"\xa24x\xe7\xcf\xd3\xff6\xff\x80\xe3hA\\X\xc0" +
-	"\xdb\xaa6\xa0\xdc\xcf\x8c\x95*\x9a\xf9\xa4\xd6\xbc\xa4g" +
-	"\xe3\xa7~T<\xe0\x97\xabX\xc0\xa5il'\xbf\xd3" +
-	"\xdd}\xf2\xfc\xa9Co\x17%\xea\xc2\xe9\x13\x0595" +
-	"\x9dfNN\xa7\x99[&n?|\xd8\x88\xbdK!" +
-	"\xbcn\xe3\xcfL\xff'\x94?d\xd6\xbf\x9a\xfe\x17\x80" +
-	"\xe9G\xde\xef\x9e3\xfb\x17\xaf\xbc[\xdc\x8fm\xd5\xcc" +
-	"\x8f\xbd\xd5l\xe3'W\xbc\xf9\x99\xcd\xbd_\xfcqq" +
-	"\xdb\xea\x19\xcc\xb6m\x06\xf3y\xef\xa1\xb5\x1f\\\xfc\xd1" +
-	"\x92\x7f,n\xbb\xa5\x8e\xd9\xee\xaec\xb6/\x1c8\xba" +
-	"\xbdz\xe3\x9e3\x05Qv\x8a\xe4\xf1\x05\xffj\x94\xaf" +
-	"\xfa\xc9\xe3\xcb~J\xdb7\xce\xee\xf9\xfeg\xcf\xf8\xcf" +
-	"\x17\x0b\xf3\xb9\xc6=(_md\xc6\x8d\x14\x8c\x13\x1b" +
-	"z~\xb0}^\xe5\x05P\xfc\x88i\xed\xefV\xec\x97" +
-	"\xefy\xe9\xd7\xd9\xd0\xf57\xfd\x0b\xca#Md=\xdc" +
-	"D\xd653\x17\x7f\xad\xef\xcb\xdf\xff\x0fNz5\xb1" +
-	"T<\xd7\xf4*9m\xcdUPl2L\xfaU\xf3" +
-	"\xb7Qv\xb7P\x1e\xdc\xd3BS\x9f\x1d\x12SgJ" +
-	"\"\x1f\x15\x9f:\xd9\xc2\xe2\xb1\xad\xe5\xe7\x02\\K\xc7" +
-	"\xe2Q#:s\x99V\x12\xd2\xe2\xaa\x11\x8e\xea3G" +
-	"\xebg\x1a\xa9\x98\x96\xc8\xfc\xad\x1dTcz\xac\xb5/" +
-	"\xae\xea\x09u\x90\x0cj{;\xfak\xfb\xf5@\"\xbc" +
-	"\\\xd7B\xbd\x88\x8aCt\x008\x10@*\xaf\x04P" +
-	"JET\xbc\x02\xba\xd4\xe5\x1a\xbaA@7\xa0\xf5\x18" +
-	"\xe7\xcd>\x06u\xa5\x0cs\x08&I]9UF\xaa" +
-	"\xb4\x97%\x95W\xa6{4C\x0d\xa9\x86\x0a\x00\xae\xce" +
-	"\x8e~WoG\xbf\xe2\xb1\xbcR\xbb\x00\x94%\"*" +
-	"\x11\x01\xa7`:\x8d^$8L\xf0\x90\x88\x8a!\xe0" +
-	"\x14\xe1\x1a\xc1\x02\x804Bk\x88\x88\xa8\xac\x12p\x8a" +
-	"x\x95`\x11@J\x12\x1c\x13QY#`ZM\x1a" +
-	"C\x1d\x99\xe7\xa1\xc7\xe6\x14 z\x00\xd3\xc3\xb67\xe8" +
-	"\xb1\x97\x90\xf9\xd5\xa5\x85\x92\xe8\xb1\xd7\x92Ec\x0c\xb5" +
-	"\x16\x95\x9d\xc9\x0c\x9b\xeb\xd6v'\x1e\x8d\x0e/\xd0\xe2" +
-	"\x89pT\xd4\x9b\x95F\x0a\x84\x17\x17\x03\xc8\x8b\xb1\x01" +
-	" \xf8\x14\x8a\x18\x0c\xa1\x80\x12\xa2\x17\xbf\x04 \xab\xb8" +
-	"\x1a \xb8\x84\xf0\x08\x0a\x88\x82\x17\xbf\x0c \x87\xb1\x15" +
-	" \x18\"8F\xe6\"zq\x091\x98\xe1C\x84\x1b" +
-	"\x84;\x04/\xaa\x00\xf2\x08\xd6\x00\x04#\x84\xaf\"\xdc" +
-	")zq)\x15\x0bl\x07\x08\xc6\x08_Cx\x89\xc3" +
-	"\x8b\x83\x00r\x0a\xbb\x00\x82\xab\x08\xdfD\xb8\xcb\xe9\xc5" +
-	"\x10\x80\xbc\x9e\xd9\xaf!\xfcY\xc2KK\xbc\xa8\x01\xc8" +
-	"\x9bq\x00 \xb8\x89\xf0?#\xdc\xed\xf2\xe22\xd2X" +
-	"\x86\xef\"\xfc\x10\xe1\xe3J\xbd\xb8\x1c@>\xc8\xfc\xdc" +
-	"O\xf8\x8f\x09\x1f\xef\xf6\xe2\x10\x80\xfc.\xb3\x7f\x87\xf0" +
-	"_\x12^6\xce\x8ba\x00\xf9}\xe6\xcf/\x08\xbf\x88" +
-	"\x02\xfaBZ\xcc\x18\xb28\x1c\x8d\x87\x97\x87\xf5\xa0\x06" +
-	"\x81\xf8\xa8\x16\xef\x0b\xa2\x13\x04t\x02\x06(\xdcOt" +
-	"`\x19\x08X\x06\x18HhzH\x8b\x9b\xc3\x0a\xda(" +
-	"s\xb06\xae\x85\xd4A#a\x8e\xd3\x09C5\xb4n" +
-	"-ET1m\x06\xa3\xba\xa1\xe9\x06zl\xc5\xc9\xd2" +
-	"\x81h\xd79\xaa\xe9 \x1a\x09\x9c\x00\xd8+\"\xfb\xaf" +
-	"\x09\x8c*\xda(\xe7\xb7\xc0\x90\x9a\x18\xd2\xae\xfb\x0fJ" +
-	"`\xd5H\xc6A\xb4\x7f\xf3\xd8\xca\x02\xc8\xac\x92z&" +
-	"\xd13d\xb6\xcej\x05\x04\xbdA^w%\xa2\xfa\x02" +
-	"5\x92\xd4j+\x1eW#\x11*\x1a\xa5VzVS" +
-	"\x1eN\x17Qi\xcc2\x92\xc0\xfaV\x00\xe5\x11\x11\x95" +
-	"\xb9\x02\xa6\x97%uF\xee\x9c(\x05bj\\\x1d\xce" +
-	"\xf1;7V\x13r<\xbbAa\x0bj\xb4\x99\xddZ" +
-	"*1_K\xc4\xa2zB\xa3\x0a\x869B(\xb9\xbb" +
-	"\xec\xe3\x9b\xe4^\x91[l\xd2_\x88\x84\x16h\xf1\xf0" +
-	"2\xa8Huk\xa9\xdc\x9a3\x90\xad9k\xf2jN" +
-	"\x8a\xd6\xbaJDeS^\xcdYO\xd6\xebDT~" +
-	"\x9aWsN\xc6\x01\x94\xf7D\x0cV\xa1p\xb3\xfb\x95" +
-	"_|\xacUd\xf7k\x94\x9cMuk \xa6\x12\xe8" +
-	"\xb1\x8fH\x00\xb3QB\x9f\xe2\x100\x17\x94\xf0a\x0a" +
-	"\x06\xdb.\xfa\xeb\xc9\xd2\xa7\x1c\x046]\xd4\\\xbe\x8f" +
-	"\x96\x7f;3z\xec\x18\xdfZ\xed\xbb~\x03k\xb3\x9b" +
-	"\xe2\xa3\x85\xa6\x0a\xb86\xdf\xe6\x9aE\xb5\xca,\xd5\x9a" +
-	"\x05Lk\xabb\xe1\xb8\x16\xea\x03\xb4\xf2\xdb\xf5\xb4\x96" +
-	"b+.\xff8\x84\xf7\xcd\x09k\x91P\x81\x175\x00" +
-	"J\x95\x88J]\x0e\xe3g4\xd8\xaeU\xe8\xea\xb0U" +
-	"4|\xa34Q\x91rp\x07\xd4\xc1o\xaa\xc3}\x1c" +
-	"u\x98R\\\x1d\xee\xe7\xa8\x83\x8f\xa3\x0e\x0fp\xd4a" +
-	"*G\x1d*9\xea\xf0 G\x1d\xaa8\xea0\x8d\xa3" +
-	"\x0e\x0fq\xd4\xe1a\x8e:L\x1fS\x87\xdbP\x87\x8f" +
-	"MP\x97^_g2t%\x87\xa1\xab\x8a34\xc5" +
-	"a\xe8j\x0eC\xbf\xc2a\xe8\x1a\x0eC\x9f\xe10\xf4" +
-	"\xab\x1c\x86\xae\xe50t\x1d\x87\xa1\xeb9\x0c\xdd\xc0a" +
-	"\xe8\xc61\x86\xfe.\x18*\xea-&AWp\x08\xfa" +
-	"tq\x82F8\x04\x1d\xe6\x10T\xe7\x104\xca!h" +
-	"\x8cC\xd0\x11\x0eA\xe3\x1c\x82&8\x0458\x04M" +
-	"r\x08::F\xd0\xbbw\xc0\xb6\x09\xea\xd3k;;" +
-	"\xfa\x0b\xce\x1b\xed\xc5\xce\x1b\xed\xf6yc\xad\x16J\xf6" +
-	"\xe5\x86\x91\x1f\xa2;\x901\xf5J\xb3\xe9\x9a\x9cb\x09" +
-	"c\x10#\xd6\xa1u&\x93\x9fa\x04\xb5\x89+d\x0e" +
-	"\xca\xf2z\x96G\xeb\x08\xdfj&\x8c\x08 oaD" +
-	"d\xc4}\xdeL\x18\x07\x80\xbc\x8d\xe1\xcf\x12\xfe\xa2\x99" +
-	"0N\x00\xf9\x05\x96H[\x09\xdfa&L\x09\x80\xfc" +
-	"\x12{\xee\xf3\x84\xef2\x13\xc6\x05 \xefd\xc4\xddA" +
-	"\xf8+f\xc2\x94\x02\xc8\xbb\x99=K\x8c}f\xc2\xb8" +
-	"\x01\xe4\xbd,\x01^!\xfc\x98\x990\xe3\x00\xe4#\x0c" +
-	"?L\xf8i3a\xc6\x03\xc8\xa7\x98\x9f\xef\x99\x89\xc1" +
-	"\x12\xa6\x0c@\xbe\xc0\xec\xcf\x13\xee\x10\x04\x94\xca\xc7{" +
-	"\xb1\x1c@F\xa1\x0b`\xbe b\xb0L(\xcc\xa3\xb5" +
-	"\xda\xa8\xa6\x1bv\xbe|B\xf3\xcac_\x90Z-\xdb" +
-	"\xf5\x19v\x9d\xd5o!\xd7\x1c\xff7\xb5{T\x8c)" +
-	"\x0eD\xeb\xae\x19\x1b|\x9d\xba\x11O\xe5^\x84\xb5g" +
-	"/\xc2\x9a)\xb9t#\x1e\xceq\xcb\xfc\xc7\xa2-\x12" +
-	"\xd1\x9f,\xdbJQrVJ\xce\x06W\xb7\x96\xf2\xb1" +
-	"^\xe2f\x8bA\x8f\x1a\xabe\x0eAA\x19\xa8\xbcA" +
-	"\xdb\xc1:\x9d\x89\x98\xd7\xb0\xe1D\xab\x03\x99\xe8\xbc\xfe" +
-	"\xa7\x8fU\xa0z;\xfaY\x8fmm\xad\xe4^m\xef" +
-	"\x065\xdcD\x03c\xbe\xb6\x0c\x02Z\\\xd3\x07\xb5t" +
-	"\xbf\xbds\xca#\xa2\xa3,\x9d\xc6\x9c7\x09\xf24\\" +
-	"\x01B9^#\xd4\xbaf\x96%\x86\x0aW\x09\xb5^" +
-	"\x93HW\x09\x14\x7fC\xa0us,] \xd0\xf1?" +
-	"\x04ZW\xcf\xd2)\x02\x9dW\x08\xb4\xeeT\xa5\xe3\x04" +
-	"\x96\\&\xd0\xbaX\x97^#\xd0\xf5\xdf\x04Z\xaf\x9c" +
-	"\xa4\x9d\x04\x96\xfe\x17\x81\xd6\xcb\x14i3\x81\xee\x8f\x08" +
-	"\xb4\xde\xc6H#q\x10\xca\xc7}H\xa0u/,-" +
-	"\x8e\x83\x906\xeb&T\x84\xa3z}\xfe\xb0!\x7f\xe8" +
-	"\xcf\x1f6\xe6\x0f\x9b\xf2\x87\xb3\xf2\x87\x8f\xe6\x0f\x9b\xf3" +
-	"\x87-\xf6\xd0Gn\xd4\x15\x8c\xebo\xb9-\xce\xdc\xb1" +
-	"\xd8\xef\xb9\xdc\x0d9\xaf\xdf\x9c5\x99\xa6\x99\xdd\x15)" +
-	"\xf7\xb1\xddf\x04>H}\xf3~\x11\x95\xc3\x02N\xc1" +
-	"kiO\x86\xc2\x7fI\xc9\xf6C\x11\x95c\x02N\x11" +
-	"\xae\x9a\x97*GZ\x01\x94C\"*'\x04\xa4\xfd\xce" +
-	"\xdc\xa9\x1c'\xf4\xb0\x88\xca;\x02\xd2\x863\x9d\x90\xfe" +
-	"\x9a\xb2\xe0\x98\x88\xca\xcf\x04\xa4\x1dg*!\x9d!\xdb" +
-	"\x9f\x8a\xa8\xfc\xa7\x80\xb4\xe5L#\xa4\x7f'/\xce\x8b" +
-	"\xa8\\\xa2F=\x19\x89@\xc9\xda\xa5\xd1hDSu" +
-	"D\x10\x10\x01\x03zrx\xa9\x16\xc7\xf1 \xe0x\xaa" +
-	"\xacF<\xac/\xb7\x1az5\x1eWS\xdc\xab\xab@" +
-	"t\xe9\x0am\xd0\xb0\x7f\xb7\xc2\x94\xf9\xbdbP\x8dD" +
-	"\xd0c\x07\xec\xf6\x94\xd9L5-^A\x99\x96-\x19" +
-	"f\xc8s\x8f\x0e\x94_\xd9\xa2\xd1\x9a{v\xc8\x97\x9b" +
-	"@bHmh\x9ae)\xc5MRCIj\xf1\xd4" +
-	"\xe3\xf1\xb0\xa1\xb9\xe2a\xb5\xe0\xb5\xc2\x1e\x00\xc5#\xa2" +
-	"r\x9f\x80\xe9\xe1\xb0\x1e\x1eN\x0e/@5\x12\x0e\xf5" +
-	"\xebF\xd8\x15\xb1%\xedf\x9f\x16\xcc\xca\x85VX\"" +
-	"[\x8b\x95\xc8\x81\xec\x05\xd1:\x81D\x92D\xd4\xd6\xc1" +
-	"\\\xdd\xb9\xfd\x0b\xb5;p\xbaz\xd4\xecGz9\xfd" +
-	"\x88R\xbc\x1f\x99\xcf\xe9G\x82\x9c~\xa4\x8f\xd3\x8f\xf4" +
-	"s\xfa\x91\x05\x9c~\xe4\x8b\x9c~\xe4)N?\xb2\x90" +
-	"\xd3\x8f\x0cp\xfa\x91?\xe0\xf4#\x8b\xc6\xfa\x91\xbb\xd7" +
-	"0\x17\xb9\x95\xcd^\x9b\xa3Z\x90q\x03EndW" +
-	"\x00(u\"*\x8f\x09\x98\xce$\xdc\x93*\x88\xf6\x95" +
-	"hz4\x9b\xfcP\x11.\x96\xfd\xb7\xf063\xe3\x96" +
-	"h0\xb7\xca,\xb7:I\x10f\x8b\xa8\xcc\xcb)\x04" +
-	"OPu\xe8\x10Q\xe9\xcd\xa4\x0c\xa9L\xcfj\x00e" +
-	"\x9e\x88\xcaS\x02\xfa\x8cUz\x0e/2\xfc\xb9\xe11" +
-	"\xfcN$}C\xa6\xa5\xf2\xe2\x84\xe2=U\x05\xa7\xa7" +
-	"\xf2pz*\x89\xd3SM\xe4\xf4T2\xa7\xa7\xf2r" +
-	"z\xaa{8=\xd5\xa78=\xd5$NO\xf5{\x9c" +
-	"\x9e\xea^NO\xf5iNO5y\xac\xa7\xfa\xc4^" +
-	"\xb05\x99\x826\x8b#h\x8f\x16\x17\xb4f\x8e\xa0\xb5" +
-	"p\x04\xad\x95#h\x9f\xe5\x08\xdac\x1cA\xfb\x1cG" +
-	"\xd0~\x9f#h\x01\x8e\xa0\xcd\xe6\x08Z\x1bG\xd0\xda" +
-	"\xc7\x04\xedwC\xd0F\x93\xa0\xd5\x1c\x82\xd6\x14'\xe8" +
-	"g8\x04}\x84C\xd0\x19\x1c\x82\xd6r\x08:\x93C" +
-	"\xd0:\x0eA\xeb9\x04m\xe0\x10\xd4\xcf!h#\x87" +
-	"\xa0Mc\x04\xbd{\xb7Rm\xc9@\xe6\x9b\xa4^\xd6" +
-	"VX\xdf!\xd0\x91e\x91\x88\xca\x90}\xbc\xd2Z\xed" +
-	"\xef\xa1\xcc\x9bU)\xbc\xd4\xfe\x1aJ\x12\x85L\xb3<" +
-	"2\x90\xfd\xe8i\xab\x80\x81a\xcd\x18\x8a\x86\xcc\xbd*" +
-	"<\xdd\x84\xb4\x84\x11\xd6U\x03\\\xe1\xa8^\xbc=\xe2" +
-	"\x06\xe0f\x9b6v\x12\xa4\xac\xa3g\xdc\xcc+\xf5v" +
-	"\xce+\xf5\xb5\xa3,u\xf5\xeb\x9a\xd4\x1b\x1c\x1c\xdb\xd5" +
-	"\xc1\xa7\x97\x85#\x11\x8as\xe6\xe4\xe8\xcaF\xbcx\x0b" +
-	"i9\xb2\xda\xfe\xc4\xe0\xb7w,t\xe9\xf5\xf5J]" +
-	"\xa64m\x02\x90\xdbXiz\x8cro\xaeY\x9a6" +
-	"\x03\xc8\x9d\xac4u\x10\xde\x9b-M\x7f\x08 \xf7\xb0" +
-	"\xd4\x9eKp\x9fY\x9a\xbe\x06 +\x0c\x9fG\xf8S" +
-	"fiz\x16@\xeeg\xa5\xa9\x97\xf0Efi\xda\x02" +
-	" /d)oWD*M\xcf\xb1\x8aH%h\x11" +
-	"\xe1Cfi\xfa:\x80\xac\xb1\xd2\x112\x8f\xaf\xac4" +
-	"me\xe7\xd7\x01\xb3d\xed0K\xd36v\xbel5" +
-	"\xcf\x97?0K\xd37\x00\xe4\xef2\xfb}\x84\x9f0" +
-	"K\xd3v\x00\xf98\xf3\xe7\x98Y\xb2\xee|\x09\xfa\xff" +
-	"\xf2\xd2\xc9\xce\x06\xd1P\x15\x07\xe6|\x02+aW\xee" +
-	"wRy7J9\x9f{\xd97Jy\x1f\xe1\xe4\x7f" +
-	"\xc3dMz\xb7?\x95\x9ce\xea\xf8\xe3\x1c\x1d\xef(" +
-	"\xae\xe3\x9d\x1c\x1d\x9f\xc3\xd1\xf1\xcfst|.G\xc7" +
-	"\x9f\xe0\xe8x\x17G\xc7\xbb9:>\x8f\xa3\xe3=\x1c" +
-	"\x1d\x7f\x92\xa3\xe3_\x18\xd3\xf1[O\xaa\xff\x0d\x00\x00" +
-	"\xff\xff\x8b\x9a]\xf6"
+type PDUResult capnp.Struct
+type PDUResult_Which uint16
+
+const (
+	PDUResult_Which_accepted PDUResult_Which = 0
+	PDUResult_Which_rejected PDUResult_Which = 1
+)
+
+func (w PDUResult_Which) String() string {
+	const s = "acceptedrejected"
+	switch w {
+	case PDUResult_Which_accepted:
+		return s[0:8]
+	case PDUResult_Which_rejected:
+		return s[8:16]
+
+	}
+	return "PDUResult_Which(" + strconv.FormatUint(uint64(w), 10) + ")"
+}
+
+// PDUResult_TypeID is the unique identifier for the type PDUResult.
+const PDUResult_TypeID = 0xef768a1efec566f1
+
+func NewPDUResult(s *capnp.Segment) (PDUResult, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 2})
+	return PDUResult(st), err
+}
+
+func NewRootPDUResult(s *capnp.Segment) (PDUResult, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 8, PointerCount: 2})
+	return PDUResult(st), err
+}
+
+func ReadRootPDUResult(msg *capnp.Message) (PDUResult, error) {
+	root, err := msg.Root()
+	return PDUResult(root.Struct()), err
+}
+
+func (s PDUResult) String() string {
+	str, _ := text.Marshal(0xef768a1efec566f1, capnp.Struct(s))
+	return str
+}
+
+func (s PDUResult) EncodeAsPtr(seg *capnp.Segment) capnp.Ptr {
+	return capnp.Struct(s).EncodeAsPtr(seg)
+}
+
+func (PDUResult) DecodeFromPtr(p capnp.Ptr) PDUResult {
+	return PDUResult(capnp.Struct{}.DecodeFromPtr(p))
+}
+
+func (s PDUResult) ToPtr() capnp.Ptr {
+	return capnp.Struct(s).ToPtr()
+}
+
+func (s PDUResult) Which() PDUResult_Which {
+	return PDUResult_Which(capnp.Struct(s).Uint16(4))
+}
+func (s PDUResult) IsValid() bool {
+	return capnp.Struct(s).IsValid()
+}
+
+func (s PDUResult) Message() *capnp.Message {
+	return capnp.Struct(s).Message()
+}
+
+func (s PDUResult) Segment() *capnp.Segment {
+	return capnp.Struct(s).Segment()
+}
+func (s PDUResult) EventID() (string, error) {
+	p, err := capnp.Struct(s).Ptr(0)
+	return p.Text(), err
+}
+
+func (s PDUResult) HasEventID() bool {
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s PDUResult) EventIDBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(0)
+	return p.TextBytes(), err
+}
+
+func (s PDUResult) SetEventID(v string) error {
+	return capnp.Struct(s).SetText(0, v)
+}
+
+func (s PDUResult) Index() uint32 {
+	return capnp.Struct(s).Uint32(0)
+}
+
+func (s PDUResult) SetIndex(v uint32) {
+	capnp.Struct(s).SetUint32(0, v)
+}
+
+func (s PDUResult) SetAccepted() {
+	capnp.Struct(s).SetUint16(4, 0)
+
+}
+
+func (s PDUResult) Rejected() (PDUResult_Error, error) {
+	if capnp.Struct(s).Uint16(4) != 1 {
+		panic("Which() != rejected")
+	}
+	p, err := capnp.Struct(s).Ptr(1)
+	return PDUResult_Error(p.Struct()), err
+}
+
+func (s PDUResult) HasRejected() bool {
+	if capnp.Struct(s).Uint16(4) != 1 {
+		return false
+	}
+	return capnp.Struct(s).HasPtr(1)
+}
+
+func (s PDUResult) SetRejected(v PDUResult_Error) error {
+	capnp.Struct(s).SetUint16(4, 1)
+	return capnp.Struct(s).SetPtr(1, capnp.Struct(v).ToPtr())
+}
+
+// NewRejected sets the rejected field to a newly
+// allocated PDUResult_Error struct, preferring placement in s's segment.
+func (s PDUResult) NewRejected() (PDUResult_Error, error) {
+	capnp.Struct(s).SetUint16(4, 1)
+	ss, err := NewPDUResult_Error(capnp.Struct(s).Segment())
+	if err != nil {
+		return PDUResult_Error{}, err
+	}
+	err = capnp.Struct(s).SetPtr(1, capnp.Struct(ss).ToPtr())
+	return ss, err
+}
+
+// PDUResult_List is a list of PDUResult.
+type PDUResult_List = capnp.StructList[PDUResult]
+
+// NewPDUResult creates a new list of PDUResult.
+func NewPDUResult_List(s *capnp.Segment, sz int32) (PDUResult_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 8, PointerCount: 2}, sz)
+	return capnp.StructList[PDUResult](l), err
+}
+
+// PDUResult_Future is a wrapper for a PDUResult promised by a client call.
+type PDUResult_Future struct{ *capnp.Future }
+
+func (f PDUResult_Future) Struct() (PDUResult, error) {
+	p, err := f.Future.Ptr()
+	return PDUResult(p.Struct()), err
+}
+func (p PDUResult_Future) Rejected() PDUResult_Error_Future {
+	return PDUResult_Error_Future{Future: p.Future.Field(1, nil)}
+}
+
+type PDUResult_Error capnp.Struct
+
+// PDUResult_Error_TypeID is the unique identifier for the type PDUResult_Error.
+const PDUResult_Error_TypeID = 0xd823486e61cb3663
+
+func NewPDUResult_Error(s *capnp.Segment) (PDUResult_Error, error) {
+	st, err := capnp.NewStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2})
+	return PDUResult_Error(st), err
+}
+
+func NewRootPDUResult_Error(s *capnp.Segment) (PDUResult_Error, error) {
+	st, err := capnp.NewRootStruct(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2})
+	return PDUResult_Error(st), err
+}
+
+func ReadRootPDUResult_Error(msg *capnp.Message) (PDUResult_Error, error) {
+	root, err := msg.Root()
+	return PDUResult_Error(root.Struct()), err
+}
+
+func (s PDUResult_Error) String() string {
+	str, _ := text.Marshal(0xd823486e61cb3663, capnp.Struct(s))
+	return str
+}
+
+func (s PDUResult_Error) EncodeAsPtr(seg *capnp.Segment) capnp.Ptr {
+	return capnp.Struct(s).EncodeAsPtr(seg)
+}
+
+func (PDUResult_Error) DecodeFromPtr(p capnp.Ptr) PDUResult_Error {
+	return PDUResult_Error(capnp.Struct{}.DecodeFromPtr(p))
+}
+
+func (s PDUResult_Error) ToPtr() capnp.Ptr {
+	return capnp.Struct(s).ToPtr()
+}
+func (s PDUResult_Error) IsValid() bool {
+	return capnp.Struct(s).IsValid()
+}
+
+func (s PDUResult_Error) Message() *capnp.Message {
+	return capnp.Struct(s).Message()
+}
+
+func (s PDUResult_Error) Segment() *capnp.Segment {
+	return capnp.Struct(s).Segment()
+}
+func (s PDUResult_Error) Errcode() (string, error) {
+	p, err := capnp.Struct(s).Ptr(0)
+	return p.Text(), err
+}
+
+func (s PDUResult_Error) HasErrcode() bool {
+	return capnp.Struct(s).HasPtr(0)
+}
+
+func (s PDUResult_Error) ErrcodeBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(0)
+	return p.TextBytes(), err
+}
+
+func (s PDUResult_Error) SetErrcode(v string) error {
+	return capnp.Struct(s).SetText(0, v)
+}
+
+func (s PDUResult_Error) ErrorMessage() (string, error) {
+	p, err := capnp.Struct(s).Ptr(1)
+	return p.Text(), err
+}
+
+func (s PDUResult_Error) HasErrorMessage() bool {
+	return capnp.Struct(s).HasPtr(1)
+}
+
+func (s PDUResult_Error) ErrorMessageBytes() ([]byte, error) {
+	p, err := capnp.Struct(s).Ptr(1)
+	return p.TextBytes(), err
+}
+
+func (s PDUResult_Error) SetErrorMessage(v string) error {
+	return capnp.Struct(s).SetText(1, v)
+}
+
+// PDUResult_Error_List is a list of PDUResult_Error.
+type PDUResult_Error_List = capnp.StructList[PDUResult_Error]
+
+// NewPDUResult_Error creates a new list of PDUResult_Error.
+func NewPDUResult_Error_List(s *capnp.Segment, sz int32) (PDUResult_Error_List, error) {
+	l, err := capnp.NewCompositeList(s, capnp.ObjectSize{DataSize: 0, PointerCount: 2}, sz)
+	return capnp.StructList[PDUResult_Error](l), err
+}
+
+// PDUResult_Error_Future is a wrapper for a PDUResult_Error promised by a client call.
+type PDUResult_Error_Future struct{ *capnp.Future }
+
+func (f PDUResult_Error_Future) Struct() (PDUResult_Error, error) {
+	p, err := f.Future.Ptr()
+	return PDUResult_Error(p.Struct()), err
+}
+
+const schema_b8a1e7de8a3a89ec = "x\xda\xec[\x7ft\x14U\x96\xbe\xb7\xaaC\xa7\x81\xd0" +
+	"]y\x8d\xba\x8e\xa1\x15\x02\x12\x06\x02I\xf8\x15\x10\x9b" +
+	"\x1f\x09B\x02\x9a\xa2\x09\x0a+3\x14\xe9\x824v\xaa" +
+	"Cuu\xa4\x19g\xa2\xa8\xeb\xea\x0c\x83\xa8\xec\x8a2" +
+	"3\xc2\xc2\x88\x0a;\xe0\x19=\xb8\"\xea\x0e:\xb2\x8b" +
+	"\xbbpV\xce\xc2.zpW\x8f\xba\x07\xcf\xe8\x1cu" +
+	"\xd5\x15z\xcf}\xddU\xd5\x9d\xf4\x1b\x01\x9d3:'" +
+	"\xffp\xa8\xafo\xbf\xbe\xef\xdd\xf7\xdd\xef\xde\xf7*\xe3" +
+	"V\xb0\xe9\x9e\x9a\xb2k\x18H\xea\xa9\x92~\x99k\x9f" +
+	"\xfe\xc1\xbd\xc3\x87\xfc\xc3\xed\xa0ND\xcc\x1c\xe8\xf7o" +
+	"o\x99O\x1d\xd8\x0b%\xe8\x05\xa8\xbb\xdb\xf79\xb2\x1d" +
+	">/\x00\xdb\xea\xbb\x190\xb3\xf8\xd5\x9b\x0f\xce\x9e\xbe" +
+	"\xff\x0ePG fN\xdf=\xe5\x9e7\xde\xd9\xba\x0f" +
+	"\x1a%\xaf\x0cPw\xc6w\x1f\xb2\xc1\xfd'\x01\xb0\xfa" +
+	"\xfe\xef\x00f\xb6\x04\x97Ti?\xfa\xfd\x1d4\xb8\xec" +
+	"\x0e>\xd8\xefE\x80\xba\xc1\x03.\x95\x00\xeb\xc6\x0c\xb8" +
+	"K\x06\xcc\xd4\xff0rd\xfc\x7f\x9b?\x01e\x1cf" +
+	"~\xban\xe7\x1b\x0f\x7f1\xfbi(\x91\xc8\x8f\x1a\xff" +
+	"\xe3\xc8\xe6\xfa\xc9\x8fF?\xf9\xf1@\xcd\xa3\xab\xa4\x07" +
+	"\xf6o,\xee\xc7S\xfe\x7fBv\xd4\x7f%\x00{\xdf" +
+	"O~\x8c\xdbr\xa0\xf2\xb9\x07\xa3\x9b@\xad\xc7\xbc/" +
+	"g'\xb9Z\xa9\x95\xd8F\x85\x06_\xaf\xd0\xe0\x9f\x1c" +
+	"\xfc\xf9G;\x8f\x1f\xdf\\\xcc\x93\xf7\x95'\x91\x95\x94" +
+	"\x931\x96\x93q\xc35l\xb5g\xef\xbf>T|\x8a" +
+	"K\xcb\xf9\x14S\xe5|\x8a\x95\xffy\xff\xb1\x93\xf7\x8c" +
+	"\xdaR\xdc6}\xd1P\xb2\xddx\x11\xb7\x0d\xd7V\xaf" +
+	"~\xf9\x96\x96_\x14\xb7\xddt)\x1fw\xf7\xa5\xdc\xb6" +
+	"\xb9\xfey\xeb\xd1\xfe\x1b\xb6\x82R\x9b\x17\xa2\xac\xc3{" +
+	"\x87<\x89\xec\xd0\x10r\xf8\xa5!\xe4\xf0\xf7<7~" +
+	"\xbe\xf0\xc7\x1f\xed,>\xf0\x15!>p}\xe8y\x1a" +
+	"\x98\xfd\xf6\xf9\x1d\xf7\x0f\xb9\xf9qPF\xe4\xadr\x09" +
+	"z\x03X\x97\x1e\xb6\x00\xd9\xfaa\x17\x03\xb0M\xc3\xc2" +
+	"\x00\xec\xc3a\x17?\xffQ\xdb\xe7\x0d\xf7\xed\xdd\xa3\x8c" +
+	"\xca\xfbn\x89D\xe6\xef\x0f[\x87\x0c+\xc9\x8f3\xc3" +
+	"\xc8\x0f\xe7\x97\xd5Z\x94]\xaf\x1b\xfd\xde\x01XZ\xb7" +
+	"\xb8\xf2Id\xab+\xaf\x04\xa8[_\x99\x90 /\x10" +
+	"\xea\x08\x94\xf2B\x8e\xdeR\xdaz\xc3\xd7!SF\x90" +
+	"\xfd\xf0\x11\xbfEZ\x94\x99\xbf>Q9\xa7\xea\xd9\x1e" +
+	"\x1b\xbb\x11\xbd\x12@\x9do\xe4(\x89U\x8d$w\x86" +
+	"\x8f$wf\xb4\x9c\x8e6/~\xf9\xd9\x1e;\xcaC" +
+	"kx\xdb\xc8m\xc86s\xe3M#\xc3\x80\x99W~" +
+	"\x99\xf9\x9f\x05{=\x07z\xac\x0b_\xf0\xe7F\xaeC" +
+	"v\x94\x1b\xbf\xcaG>\xaaO^6\xff\xf6\x8b~S" +
+	"|\xc1SU|\xc1\xd7W\xf1H>\xdc\xdc|\xf4\xdd" +
+	"c\xfb^.\xbaQ7\x8e)\x97\xd8\xde14\xf2\xee" +
+	"14r}\xf9\x86\xfd\xfb\xad\xceC\xb4\x84\xbd\x02\xaf" +
+	"T\xff\x07\xb21\xd5d]U\xfd+\xc0\xcc\xe87\x9b" +
+	"gO?\xb5\xfdPq?^\xaa\xe6~\xbc^\xcd\x03" +
+	"\xff\x1d\xff\xb3\xdf\xbd\xb3\xe5\xfa\xc3\xc5m+\xea\xb8\xed" +
+	"\x84:\xee\xf3\x8e}\xdd\xef\x9c\xfe\xcd\xb2\x7f/n[" +
+	"?\x89\xdb\xaa\x93\xb8m\xdb\xc4\x7f\xd6\x8c9\xc3\x8es" +
+	"j}\xb8\xe2\xa5\xb3C\xee\xe9\xfa]\xce\xe1\xd6\xa9O" +
+	"\" \xd3\xa7\xd2\xd46\xee=\xb0\xa1\xea\xf6m'z" +
+	"D\xbb\x84\xc7\xe3\xed\xa9k\x91}6\x95\xfe\xfb\xf1\xd4" +
+	"\xeb)\xd8O\xbf\xb1\xed\x89\xa9'\xea\xde-\x16\x90\xa5" +
+	"\xd3\xb6!KM\xa3\x85X=\x8d\xc6>\xb8n\xfe\xae" +
+	"\x0d\xf3\x86\xbe\x07j\x1dbF\xff\x97U{\xd8\xe0M" +
+	"\xbf\xcf-\xf2\xab\xd3\xfe\x0b\xd9{\xdc\xfamn\xedx" +
+	"\xd9+\xd5H(\xd55^\xbd\x0e\xd9\xe2\xab\x89\x05\xda" +
+	"\xd5\x94jF\x8d]z\xd7\xc2\xef?\xf1\xbb\xe2\xab1" +
+	"-\xcc9\xde\x1a\xbe\x8eV\xc3\xf9\xe9\x1eCg\xb7h" +
+	"\xc5\xac\x87\x90M\x98EC\xcf\x98E\x9e\xbc\xd1.\xa7" +
+	"O\xf4\x8b\x7fR|\xe8\xad\xb3\xf8B?3\xeb.\x19" +
+	"\xcef:\xcd\x84\x95\x18\xbbB\xef\x17\xd5M\xcd\x8a%" +
+	"\x8c\xb1]5c\xadt\xa7\x9e\xcc\xfe[\xdd\xa6u\x1a" +
+	"\x9dS\x16\x9a\x9a\x91\xd4\xda\xc8\xa0\xba\xa5\xa1\xb5\xba\xd5" +
+	"\x08'c+\x0d=\xda\x82\xa8\x96\xca\x1e\x00\x0f\x02(" +
+	"UC\x01\xd4J\x19\xd5q\x12\"\x06\x91\xb01S\x00" +
+	"\xd4\x912\xaa\xe3%\xf4j+u\xf4\x81\x84>\xc0p" +
+	"b\xf9*\xbd\xcd\xc2\x80\xcbX@\x0c\x00:>\x95\x9c" +
+	"\xabOh\xa8\x031o\x9b+JS^\xaeS\x86\xba" +
+	"k\xa0\x94\x0d\xcd\xcc\xd7--\xaaY\x1a\x00x\x1b\x1b" +
+	"Z\xbd-\x0d\xadj\xc0\x99\x82\xd6\x04\xa0.\x93Q\x8d" +
+	"KX\x81\x99Ln\x161\x82\xdbeT-\x09+\xa4" +
+	"\xb3\x04K\x00\xcaj\x9ap\\Fu\x8d\x84\x15\xf2\x19" +
+	"\x82e\x00%Ep\xa7\x8c\xea-\x12f\xb4\x94\xd5\xde" +
+	"\x90\xfd=\x0c\xb8\x1b67\xd9\x0e\xd7\x1b\x0c\xb8S\xc8" +
+	"~\xea\xd5\xa3)\x0c\xb8s\xc9\xa1\x9d\x1cu&\xd5c" +
+	"\xd9\xbc\xe7\x17J3\x91\xe8X\xa4\x9b\xc9XB6&" +
+	"\xab\xd3i!\x82\xb8\x14\x80\xed\xc5Z\x80\xc8.\x941" +
+	"\xb2\x0f%T(\xa0\xdf\x03`O\xe1Z\x80\xc8\xaf\x09" +
+	"\x7f\x01%D)\x88\xdf\x07`\xcf\xe1\x14\x80\xc8>\x82" +
+	"\x0f\x92\xb9\x8cA\\\x06\xc0^\xe4\xf8~\xc2_!\xdc" +
+	"#\x05Q#\x95\xc1Q\x00\x91\x17\x08?Lx\x89\x1c" +
+	"\xc4\xe5\x00\xec\x10\xce\x04\x88\x1c$\xfc\x08\xe1\xfd<A" +
+	"l\xa3$\x89M\x00\x91\xc3\x84\x1f'\xdc[\x12\xc4(" +
+	"\x00;\xc6\xed\x8f\x10~\x92\xf0\xd2~A\xd4\x01\xd8\x09" +
+	"\\\x02\x109N\xf8G\x84\xfb\xbcA\\A\xf2\xc3\xf1" +
+	"\x0f\x08\x1f(I\xa8\xf4/\x0d\xe2J\x00\xe6\x93\xc8O" +
+	"\x8f$c\xe4r\xc2\x07\xf8\x82\xd8\x0e\xc0*$\xb2\xbf" +
+	"\x8c\xf0\xc9\x84\x0f\xec\x1f\xc4\x18\x00\x9b \x91?\xe3\x09" +
+	"\x9fNx\xd9\x80 \xee\x00`\xd3\xf88\x93\x09o " +
+	"|\xd0\xc0 \xfe\x92\xb8\xc9\xc7\x99N\xf8<\xc2\xfde" +
+	"A|\x14\x80\xcd\x95\x16\x00D\xe6\x10\xbeP\x920\x14" +
+	"\xd5;\xadv\x9b$\x99\x84\x19[\x193\":\x84\xcd" +
+	".\xdd\\\x18\xc1\x12\x90\xb0\x040La\x9b\xdb\x80\x03" +
+	"A\xc2\x81\x80\xe1\xa4nDu\xd3~\xf4S\xc0\xed\x87" +
+	"nS\x8fjmV\xd2~\xce$-\xcd\xd2\x9b\xf54" +
+	"m9\xdb\xa6-aX\xbaQ\x8c\x8d\xb4}\x1b\xbbt" +
+	"\x03d+\x89\x83\x00[d\xe4\xdf\x1a\xc4\xb7\x9c\xde%" +
+	"\xf8,\xdc\xae%\xdb\xf5^\xdf\xa0\xac\xa1Y)\x13d" +
+	"\xf7\xb3\x80\xab\x93\x80\xdc*ed\xb3K\x96\x14N\xe9" +
+	"\x99\xf5(\x9c]\x13g:\x1dz\xc7r\xddL\xb6\x83" +
+	"\x1c\xebt@\xf2,bi\x16\xa0~\xe1\x19\xa6)\x99" +
+	"0\x16i\xf1\x94^\xed\x9f\xa5\xc5\xe3=r]\x93\x9b" +
+	"\xd7\x14;\xd9\xd5P\xb2\x1b-\xa3:G\xc2\xcc\x8a\x94" +
+	"\xc1i\x96\xb7\xce\xe1N\xcd\xd4:\xf2f\x9e\xef\xd9\xa0" +
+	"<\xcf\xbe$\x1fGt\xda\x0e\xcdz:\xb9@Ov" +
+	"&\x8c\xa4\x0e\xa0\x96b^a\xa0\xf8\x9a\xdcrV\xf1" +
+	"\xad\xcaO{\x99\xeb\xe2\xd1E\xba\x19[\x01\xfet\xb3" +
+	"\x9e\xce\xcf~Kr\xd9\xef\x96\x82\xec\x97\xa6\xb9\xae\x91" +
+	"Q\xbd\xa3 \xfb\xddF\xd6\xb7\xca\xa8\xbeV\x90\xfd\x8e" +
+	"\x9a\x00\xea\x11\x19#\x95(\x9dk\xc4\x0b\xd3\xa03\x8b" +
+	"\\\xbc\xba\xc8\xd9t\xb3\x0er:\x89\x01\xb7d\x04\x98" +
+	"\x8e\x0a\x86T\x8f\x84\xf9\xa0\x82W\xd2b\xf0p\xd1\xbf" +
+	"\x81\xdc\x06,\x03\x89\x0f\x97\xb0\xa7\x1f\xa2\xe9\x7f\x95\x11" +
+	"\x03\xee\x1a\x9f_\x16\xee\x1d\xc0\xea\\PB4\xd1t" +
+	"\x8f\xbd\xb6\xc0\xddk\xceV\x1b\x9a\xdbj\x93%\xcc\xe8" +
+	"k:c\xa6\x1e]\x08\xe8d\x08\xefMz\x9a\xcf\xb8" +
+	"\xecB6|hvL\x8f\xf7T\xf7Q\xae\xba;;" +
+	"~L\xad\xeb\x9a\xdf\xd0:\x9c\xb4\x13\xea\xa2\x81\xfe\x00" +
+	"\xf9\xbe\x82N\xd5\xd9:u\x99@\xa7*\x8a\xeb\xd4\x10" +
+	"\x81N\x85\x04:u\xb9@\xa7\xae\x10\xe8\xd4P\x81N" +
+	"\x0d\x13\xe8T\xa5@\xa7\x86\x0btj\x84@\xa7\xae\x14" +
+	"\xe8\xd4H\x81N\xfd\x8d@\xa7\xfeV\xa0S\x0f\xf6\xe9" +
+	"\xd4\x9fT\xa7.\x98*^\xa3f\x9c\xcd\x95\x9b\x05\\" +
+	"YS\x9c+i\x01W\xd6\x0a\xb8\xf2\x03\x01Wn\x11" +
+	"p\xe5\x87\x02\xae\xfcH\xc0\x95n\x01Wn\x15p\xe5" +
+	"6\x01W\xd6\x09\xb8r\xbb\x80+O\x08\xb8\xb2K\xc0" +
+	"\x95\xdd}\\\xf9vrE6\xeam\xaa\xac\x12P\xe5" +
+	"\xa6\xe2T\x89\x0b\xa8\xd2!\xa0\x8a!\xa0JB@\x95" +
+	"N\x01UV\x0b\xa8b\x0a\xa8\x92\x14P\xc5\x12P%" +
+	"%\xa0J\x97\x80*;\x05TyL@\x95\xc7\xfb\xa8" +
+	"\xf2Mn\x7f\\\xaa\x84\x8c\xea\xc6\x86\xd6\x1e\xd5\xe0\xcc" +
+	"b\xd5\xe0L\xb7\x1a\xec\xd6\xa3\xa9\x85\xf9\x81\x10/\xf2" +
+	"\xd7\xc0\xdd\x1a\xb5\xc1v\x8d\xbd\xca\xa9\xfb\x0a\xed\xf1\xd7" +
+	"\xd0\xa9\x98\xd9QN\x15\x97BR\xb6\x8da\xc78\xa3" +
+	"_#\xfc\x94M]\x19\x80\xbd\xce\xa9\xcb)\xf4\x96M" +
+	"]\x0f\x00{\x93\xe3'\x09\x7f\xd7\xa6n\x09\x00{\x9b" +
+	"S\xfa\x14\xe1\xa7m\xea\xf6\x03`\xef\xf1\xdf}\x8b\xf0" +
+	"\x0fl\xeaz\x01\xd8\xfb\x9c\xd2\xa7\x09\xff\xd4\xa6n)" +
+	"\x00\xfb\x98\xdbs\x8a~aS\xd7\x07\xc0>\xe3\xd4\xfd" +
+	"\x94\xf0\xa0M\xdd\xfe\x00L\xe1\xd4\x0a\x10\x85F\xdb\xd4" +
+	"\x1d\x00\xc0\xaa8\x15+\x1d\x8a\x12u\x07r\x8a\x92\xfd" +
+	"U\x84\xdf`S\xb7\x0c\x80\xb5rJ/$|\x99M" +
+	"\xdd{\x01\xd8R>\xce\x0d\x84Gm\xean\x04`\x1a" +
+	"\x1fg\x19\xe1q\xc2\x03\x83\x82x\x1f\x00\x8bqJ\xb7" +
+	"\x13n\xf5\xa2t\xb7\xde\xa5\x1b\x96K\xddo(\xc5\x03" +
+	"\xee\xbd\x80\xd3\x99\xf7&{/\xabo\x05\xed=\x7f\x98" +
+	"e\xf35\xecT=\x88\xce%\x0d\xd6\x86\x1a\x0d\xcbL" +
+	"\xab\x1e\x87\xfce\xc4\xf3\xd2l\xf3\xd9\xad\x1b\x96\x19\xcb" +
+	"\x9b\x98\xfd\xc5\xa2\xbd41\x91,g\x94\xa2R2T" +
+	")\xa9\xf56\xeb\xe9\x10o:\xcf5/\xcd\xd7:\xab" +
+	"\xb9C >}.\xda\x9f\xf2\x96\xb8\x1c\x0b:{," +
+	"wZ\xd5\xf2\x92\xde\x1f]P\xaelih\xe5\x871" +
+	"\xce\xe6P|k\xddx*\xbe\xa6\x0cm$k\x81\xbe" +
+	"\x02\xc2\xba\xa9\x1bmz\xa6\xd5\x8d\xbd:Z\xf6\x0c\xcc" +
+	"d0\xef\x0a\x8e\x0d\xc7U \x95\xe1YB\x9d\xfb\x19" +
+	"\xa6pT:C\xa8s\xbf\xa8\x9c!P\xfe\x82@\xe7" +
+	"\xcaEy\x8f@\xcf\xff\x11\xe8\xdc\xd9(\xc7\x08,\xf9" +
+	"\x9c@\xe7\xce@y\x91\xc0~\x9f\x11\xe8\xdcH)\xbb" +
+	"\x09\xf4~J\xa0sW\xabl&\xb0\xf4\x7f\x09tn" +
+	"!\x95;\x09\xf4}B\xa0s\x8d\xa9\xac6A*\xeb" +
+	"\xff1\x81\xce\xbd\x87\xb2\xd4\x04)c\xa7p\xf0\xc7\x12" +
+	"FM\xe1cm\xe1c]\xe1\xe3\xf8\xc2\xc7\x09\x85\x8f" +
+	"\x13\x0b\x1f'\x15>N.|\xacw\x1fC\xe4\xc6\xb8" +
+	"\x1e\xcf5\xe7}~\x92=\x8cs/\x88}\xb5y\xf7" +
+	"\xd6%\xa3\xb2\xa7+\xfcPQ\xbd\x9cG\x9bo\xe0\xa3" +
+	"\xa3\x00\xd4\xc32\xaa\xc7%\xac\xc0\xb3\x99@v\x0b\x1f" +
+	"\x9b\x99=OSOJX!\x9d\xb1O\xdfNL\x01" +
+	"P_\x93Q=%!\xc5;{\xf8\xf6:\xa1\xc7e" +
+	"T\xdf\x92\x90\x02\xce%Ky\x93XpRF\xf5S" +
+	"\x09)\xe2\\\xb0\x94\x8f\xc9\xf6\x03\xaa\x04QB\x8ay" +
+	"V\xaf|\\\xc7<\xa43\x01\x94\xb0\x82\xe2\x9eU\xac" +
+	"2\xaeL\xa5\\\x81PB\xbf\x91\x8a\xc7\xa1_\xf7\xf2" +
+	"D\"\xaek\x06\"H\x88\x80a#E\xb9\x09\x07\x80" +
+	"\x84\x03(s[f\xccX\xe9\x9c\x0bi\xa6\xa9\xa5\x85" +
+	"'\xa0\xf6\xb5\x90\xf3\xb9\xb3\x88\xd9\xcf\xfdmZ<\x8e" +
+	"\x01w9\xb3\xc9\xad;fX\xfaJ\xdd\xb4\xd5\xe3B" +
+	"K\x0a\x9b\x98\xba\xe9'^\xe6\x12\x8c\x1d\xa0\xfc\x9a\x87" +
+	"\xd8\xd8\xfb\x86\xab\xa7\xbc\x85\x93\xedZ\xed\x84\x89y\x19" +
+	"\xfa\x9c6\x92\x9a\xd2\xcd\xf4,3f\xe9^3\xa6\x91" +
+	"\x17y\xb9w\x1b\x80\x1a\x90Q\xbdL\xc2LG\xcc\x88" +
+	"u\xa4:\x16\xa1\x16\x8fE[\x0d+\xe6\x8d\xbb\x12z" +
+	"\xae\xbf\x16\xc9\xc9\x93\xde3\xa1N)\x96P\x97\xe4\xce" +
+	"\x1do\x95H\x94I\xb4]\xdd\xcd\xd7\xb9\xaf~N\xfb" +
+	"5\x94\x85\x93\xec\x96\xaeE\xd0\xd2\xa9\xc5[\xba\x05\x82" +
+	"\x96.\"h\xe9\x16\x0aZ\xbaVAK\xb7H\xd0\xd2" +
+	"]/h\xe9n\x10\xb4t\x8b\x05-\xdd\x12AK\xf7" +
+	"\x97\x82\x96\xeeFAK\xb7M\xd0\xd2\xfd\x9d\xa0\xa5\xdb" +
+	"\xde\xd7\xd2}\x93O?\x8a\\;\xe4\xee\x85P\xeb\xc1" +
+	"\xfd%E\xae\x1cV\x01\xa8\xe3dT\xaf\x920\x93\xa5" +
+	"\xfe\xb5\x1a\xc8\xee\x99\x7f\xa6+\x97\x86\xc0\x1f+\x96\x87" +
+	"\xce\xe3-\x83\xac[\xb2\xc5\xdd\x1a\xe8\xb8\xd5HB6" +
+	"]Fu^^J\x9aKy\xaaAF\xb5%K^" +
+	"R\xc7\xf9k\x01\xd4y2\xaa7H\x18\xb2\xd6\x18y" +
+	";\xab\xc7j\x8b6\xe4\xd7\x91~j\xb3]i\x10\x07" +
+	"\x15oK\xfd\x82\xb64 hK\x15A[Z.h" +
+	"K\x99\xa0-\x0d\x0a\xda\xd2\xc1\x82\xb6\xf4\"A[z" +
+	"\xb1\xa0-\xbdD\xd0\x96\xfe\x85\xa0-\xbdT\xd0\x96~" +
+	"G\xd0\x96\xde/hK\x1f\x10\xb4\xa5\x9b\xfa\xda\xd2?" +
+	"\xe7\x83\xdb\x09\xb6\xcaO\x14\xa8\xfc\xa4\xe2*?Y\xa0" +
+	"\xf2\xf5\x02\x95\x9f\"P\xf9\xa9\x02\x95\xbfJ\xa0\xf2\xd3" +
+	"\x04*\x7f\xb5@\xe5\xc3\x02\x95\x9f.P\xf9\x19\x02\x95" +
+	"\x9f)P\xf9-\x02\x95\xff\x99@\xe5\x7f\xde\xa7\xf2\xdf" +
+	"V\xaa\x8c\xb7\xa9R%\xa0\xca\xa8\xe2T\xf9\xae\x80*" +
+	"\xa3\x05T\x19#\xa0J\xb5\x80*c\x05T\x19'\xa0" +
+	"J\x8d\x80*\xb5\x02\xaa\xd4\x09\xa82^@\x95\x09\x02" +
+	"\xaal\x16P\xe5!\x01U\x1e\xee\xa3\xca7\xf9\x8e\xa3" +
+	"\xa5\xa1u\x81\x9eL\xc5\xad\xeaP\xa3i&\xcc/\xbf" +
+	"\xe0P\xc6\x84\xec\xba\xb2[7\xcd\xb6D\xd4\x0dC\x87" +
+	"\x9eLj+u\xd5\x83R\xe6\xc3\xf5c/._\xf6" +
+	"\xcc?\x02u\xbd<\x0c\xa0\xe0\xaa\x8cN\xbf2_O" +
+	"\x82\x9f,{\x9d\x0b|\xc9\xd9\xec\x8cT8\xfb2)" +
+	"U\xc5\x978\xaen\xa6\x02\xf8\x01\x19\xd5G\xdcb\xfd" +
+	"g\x84=(\xa3\xba\xdd\xbd\xeaP\xb6.\x07P\x1f\x91" +
+	"Q\xddE\xf4\x95\xb2GF\x8fQ\xa9\xbfSF\xf5 " +
+	"q\x17\xb3'F/R\xfd\xfc\x82\x8c\xeaa\xf7\x86C" +
+	"9D\xd5\xf7A\x19\xd5#\x12\x86;t\xab=\x11u" +
+	"_\xe7-\x0cYTOZ1C\xb3\xc0\x1bK\x18\xc5" +
+	"\x0f\x08\x84{C\xc4\x88\x90\x910\xda\xf4\xf3}\xa9)" +
+	"\xdb\xf3P\xfe#W\xce\xe5\x95\xa6\x99\x82W\x9a\xba\xbb" +
+	"x\x125zE\xedK\xfa\x9a\x99Z\xdbM+b\xf1" +
+	"8\x05.\xdb\xd8xs!,~\xd6\xe28\xb2\xd6}" +
+	"\xc5\xeb\xbc\xbb\x96s\xdd\xf8\xa0z0\xff\x85{\xac\xcd" +
+	"1!\xef\xad@Z\x90\x1beT\xdb]\xdf\xf4Z\xf7" +
+	"=\xe92)\x93\xc9\xee\xaf\x82\xd7\xa4\xe5\xb3\xf6\x1b\x81" +
+	"\xab\x9b\xdc\xf7\xa1{V\xda\xa1\x98\x11\xd5\xd7`)H" +
+	"XJ\xb9\xa8\xadM\xef\xb4(/x3\xa6\xbeJo" +
+	"\xb3\xec\x1c\xe1\xb8\xf85\xbe\x1cS\xa3^\x95U\xc3;" +
+	"\x00\xd8&\xae\x86\xf7\x92ll\xb1\xd5\xf0N\x00\xb6\x99" +
+	"\xab\xe1\x83\x84o\xcf\xa9\xe1_\x01\xb0\xad\\\xf5\xb6\x10" +
+	"\xbc\xd3V\xc3\xbb\x00\xd8\x0e\x8e?B\xf8.[\x0d\xff" +
+	"\x1a\x80=\xc6\xd5p;\xe1{l5\xbc\x1b\x80\xed\xe6" +
+	"\xaa\xe7\x8a0\xa9\xe1=\\\x84I\xf5\xf6\x10\xbe\xdfV" +
+	"\xc3\x1f\x03\xb0g\xb8\xba\xed\xb3\xdbH\xae\x86?\xe1}" +
+	"\xe4\x12[%O\xdbj\xb8\x9e\xf7ySr}\xde\x02" +
+	"[\x0c\x7f\x0a\xc0\xcep\xf3/\xc8\xfc\x12[\x0c7\x00" +
+	"\xb0\xc1\\\xf4\x82\x8eH\x92\x18\xfe=\x17I\x1a\xe6\x12" +
+	"\xc2+m1\xfc\x15\x00\xbb\x82\x8b\xde\xe5N\x9bGb" +
+	"\xb8\x87\xb7y$z#\x09\x1f\xffG\x11\xbd>\x91;" +
+	"W\xae\xbb\xf9G\xb64Nw\xe7O[\x14l\xca\x7f" +
+	"3\xb8\xe0\xb0;\xef\x05g\xf7\xb0\xbb\xe0\xb5\xd3\xc2\xb7" +
+	"v\x9dA\xff\xd8\x7f\xa60\xd1\xaeag\x09j\xd8\x86" +
+	"\xe25l\xa3\xa0\x86\x9d-\xa8a\xaf\x11\xd4\xb0s\x04" +
+	"5\xec\\A\x0d\xdb$\xa8a\x9b\x055\xec<A\x0d" +
+	";_P\xc3^+\xa8a\xaf\x13\xd4\xb0\xbf\x10\xd4\xb0" +
+	"\x8f\x08j\xd8\xad}5\xec\x9f\x82\xde\xff\x1f\x00\x00\xff" +
+	"\xff\xab\x1a[&"
 
 func RegisterSchema(reg *schemas.Registry) {
 	reg.Register(&schemas.Schema{
@@ -5846,9 +6885,11 @@ func RegisterSchema(reg *schemas.Registry) {
 			0xc9a4e040464be12c,
 			0xcc5750852bbb0f1b,
 			0xd760c3ece77fb8a5,
+			0xd823486e61cb3663,
 			0xd9a283298fbeb191,
 			0xe833d93baba2deb6,
 			0xe9224c8fac4d82c4,
+			0xef768a1efec566f1,
 			0xefab5f54875d2f2a,
 			0xf2951513b06ace65,
 			0xf66c06d9790368de,
@@ -0,0 +1,220 @@
+package rpcserver
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	capnp "capnproto.org/go/capnp/v3"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// ErrUnauthenticated is wrapped by every error returned when the AuthData of a call is not acceptable.
+var ErrUnauthenticated = errors.New("request authentication failed")
+
+// A VerifyKeySource returns the keys of other servers that were valid at a given POSIX timestamp in milliseconds.
+// It returns ErrKeyNotFound if the key is unknown.
+type VerifyKeySource interface {
+	VerifyKeyAt(serverName string, keyID KeyID, ts int64) (ed25519.PublicKey, error)
+}
+
+// SetVerifyKeySource sets where the keys of other servers are looked up when checking their signatures
+func (s *RPCMatrixServer) SetVerifyKeySource(source VerifyKeySource) {
+	s.verify_key_source = source
+}
+
+// Length of the random nonce of AuthData created by NewAuthData
+const nonceLength = 16
+
+// NewAuthData creates the AuthData for a call of the given method to destination and signs it with the signing key.
+// The signing key's server name is used as origin. The current time and a random nonce are included so the
+// request can not be replayed. A new AuthData has to be created for every request.
+func NewAuthData(s *capnp.Segment, methodID uint64, destination string, signingKey SigningKeyWrapper) (types.AuthData, error) {
+	auth_data, err := types.NewAuthData(s)
+	if err != nil {
+		return auth_data, err
+	}
+	auth_data.SetMethod(methodID)
+	err = auth_data.SetOrigin(signingKey.ServerName())
+	if err != nil {
+		return auth_data, err
+	}
+	err = auth_data.SetDestination(destination)
+	if err != nil {
+		return auth_data, err
+	}
+	auth_data.SetOriginServerTS(time.Now().UnixMilli())
+	nonce := make([]byte, nonceLength)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return auth_data, err
+	}
+	err = auth_data.SetNonce(nonce)
+	if err != nil {
+		return auth_data, err
+	}
+
+	message, err := signedAuthData(auth_data)
+	if err != nil {
+		return auth_data, err
+	}
+	signatures := NewSignatureListBuilder()
+	err = signatures.Sign(signingKey.ServerName(), signingKey.KeyID(), signingKey.privateKey, message, types.AuthData_TypeID)
+	if err != nil {
+		return auth_data, err
+	}
+	signatures_list, err := signatures.Build(s)
+	if err != nil {
+		return auth_data, err
+	}
+	return auth_data, auth_data.SetSignatures(signatures_list)
+}
+
+// signedAuthData returns a copy of the signed part of the AuthData, which is everything but the signatures.
+func signedAuthData(auth_data types.AuthData) (capnp.Struct, error) {
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	signed, err := types.NewRootAuthData(seg)
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+
+	signed.SetMethod(auth_data.Method())
+	origin, err := auth_data.Origin()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	err = signed.SetOrigin(origin)
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	destination, err := auth_data.Destination()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	err = signed.SetDestination(destination)
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	signed.SetOriginServerTS(auth_data.OriginServerTS())
+	nonce, err := auth_data.Nonce()
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+	err = signed.SetNonce(nonce)
+	if err != nil {
+		return capnp.Struct{}, err
+	}
+
+	return capnp.Struct(signed), nil
+}
+
+// authenticate checks the AuthData of a call to the method with the given ID.
+// It returns the origin server on success. All returned errors wrap ErrUnauthenticated, replays additionally
+// wrap ErrReplayedRequest.
+//
+// The AuthData has to name the called method and us as destination, has to be made within the replay window
+// with a nonce not seen before, and has to carry a valid signature of the origin. If the origin signed with a key we
+// do not know or whose validity ended, its keys are fetched with the KeyFetcher before the request is rejected.
+// The fetcher shares fetches and reuses their outcome for a while, so requests can not make us fetch keys over and
+// over again.
+func (s RPCMatrixServer) authenticate(ctx context.Context, methodID uint64, auth_data types.AuthData) (string, error) {
+	if !auth_data.IsValid() {
+		return "", fmt.Errorf("%w: missing auth data", ErrUnauthenticated)
+	}
+
+	if auth_data.Method() != methodID {
+		return "", fmt.Errorf("%w: auth data is for method %#x but %#x was called", ErrUnauthenticated, auth_data.Method(), methodID)
+	}
+
+	destination, err := auth_data.Destination()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+	if destination != s.keys.SigningKey().ServerName() {
+		return "", fmt.Errorf("%w: destination %q is not this server", ErrUnauthenticated, destination)
+	}
+
+	origin, err := auth_data.Origin()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+	if origin == "" {
+		return "", fmt.Errorf("%w: missing origin", ErrUnauthenticated)
+	}
+
+	err = s.nonces.checkTimestamp(auth_data.OriginServerTS())
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+	nonce, err := auth_data.Nonce()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+
+	message, err := signedAuthData(auth_data)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+	signatures, err := auth_data.Signatures()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+
+	result, err := VerifyCapnproto(message, types.AuthData_TypeID, signatures, []string{origin}, s.keyLookupAt(time.Now().UnixMilli()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+	if !result.Valid() && len(unknownKeyServers(result)) > 0 && origin != s.keys.SigningKey().ServerName() && s.key_fetcher != nil {
+		err = s.key_fetcher.FetchKeys(ctx, origin)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w: unable to fetch the keys of %s: %w", ErrUnauthenticated, result.Err(), origin, err)
+		}
+		result, err = VerifyCapnproto(message, types.AuthData_TypeID, signatures, []string{origin}, s.keyLookupAt(time.Now().UnixMilli()))
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+		}
+	}
+	if err := result.Err(); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+
+	// Only remember nonces of valid requests, otherwise anyone could block nonces of other servers
+	err = s.nonces.checkNonce(origin, nonce, auth_data.OriginServerTS())
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+
+	return origin, nil
+}
+
+// keyLookupAt returns a KeyLookup for keys valid at the given timestamp.
+// Our own keys are taken from the key ring, the ones of other servers from the verify key source.
+func (s RPCMatrixServer) keyLookupAt(ts int64) KeyLookup {
+	return func(serverName string, keyID KeyID) (ed25519.PublicKey, error) {
+		if serverName == s.keys.SigningKey().ServerName() {
+			verify_keys := s.keys.VerifyKeys()
+			index := slices.IndexFunc(verify_keys, func(k SigningKeyWrapper) bool {
+				return k.KeyID() == keyID
+			})
+			if index >= 0 {
+				return verify_keys[index].PublicKey(), nil
+			}
+			if old_key, ok := s.keys.OldVerifyKeys()[keyID]; ok && ts < old_key.ExpiredTS {
+				return old_key.Key, nil
+			}
+			return nil, ErrKeyNotFound
+		}
+
+		if s.verify_key_source == nil {
+			return nil, ErrKeyNotFound
+		}
+		return s.verify_key_source.VerifyKeyAt(serverName, keyID, ts)
+	}
+}
@@ -0,0 +1,146 @@
+package rpcserver
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	capnp "capnproto.org/go/capnp/v3"
+	"capnproto.org/go/capnp/v3/rpc"
+
+	protocol "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// memoryKeys keeps fetched keys until their valid_until_ts, like keystore.KeyStore without persistence
+type memoryKeys struct {
+	mu      sync.Mutex
+	servers map[string]*ServerKeys
+	stored  int
+}
+
+func (m *memoryKeys) Store(keys *ServerKeys) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.servers[keys.ServerName] = keys
+	m.stored++
+	return nil
+}
+
+func (m *memoryKeys) VerifyKeyAt(serverName string, keyID KeyID, ts int64) (ed25519.PublicKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys, ok := m.servers[serverName]
+	if !ok || keys.VerifyKeys[keyID] == nil {
+		return nil, ErrKeyNotFound
+	}
+	if ts > keys.ValidUntilTS {
+		return nil, fmt.Errorf("key %s of %s expired", keyID, serverName)
+	}
+	return keys.VerifyKeys[keyID], nil
+}
+
+// pipeDialer connects to a server running in the same process
+type pipeDialer struct {
+	server RPCMatrixServer
+}
+
+func (d pipeDialer) Dial(ctx context.Context, destination string) (*rpc.Conn, error) {
+	local, remote := net.Pipe()
+	client := protocol.MatrixFederation_ServerToClient(d.server)
+	rpc.NewConn(rpc.NewStreamTransport(remote), &rpc.Options{BootstrapClient: capnp.Client(client)})
+	return rpc.NewConn(rpc.NewStreamTransport(local), nil), nil
+}
+
+func newTestServer(t *testing.T, serverName string) (RPCMatrixServer, SigningKeyWrapper) {
+	t.Helper()
+	_, private_key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signing_key := NewSigningKey(serverName, "ed25519:1", private_key)
+	server, err := NewServer([]SigningKeyWrapper{signing_key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, signing_key
+}
+
+func newTestAuthData(t *testing.T, destination string, signingKey SigningKeyWrapper) types.AuthData {
+	t.Helper()
+	_, seg, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth_data, err := NewAuthData(seg, Backfill_MethodID, destination, signingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return auth_data
+}
+
+func TestAuthenticateFetchesKeys(t *testing.T) {
+	remote, remote_key := newTestServer(t, "remote.example")
+
+	tests := []struct {
+		name  string
+		known *ServerKeys
+	}{
+		{name: "unknown server"},
+		{
+			name: "expired key",
+			known: &ServerKeys{
+				ServerName:   "remote.example",
+				ValidUntilTS: time.Now().Add(-time.Hour).UnixMilli(),
+				VerifyKeys:   map[KeyID]ed25519.PublicKey{"ed25519:1": remote_key.PublicKey()},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			local, _ := newTestServer(t, "local.example")
+			store := &memoryKeys{servers: make(map[string]*ServerKeys)}
+			if test.known != nil {
+				store.servers[test.known.ServerName] = test.known
+			}
+			local.SetVerifyKeySource(store)
+			local.SetKeyFetcher(NewRemoteKeyFetcher(store, pipeDialer{server: remote}))
+
+			origin, err := local.authenticate(context.Background(), Backfill_MethodID, newTestAuthData(t, "local.example", remote_key))
+			if err != nil {
+				t.Fatalf("authenticate: %v", err)
+			}
+			if origin != "remote.example" {
+				t.Errorf("got origin %q, want remote.example", origin)
+			}
+			if store.stored != 1 {
+				t.Errorf("keys were stored %d times, want 1", store.stored)
+			}
+
+			// The fetched keys are used for the next request
+			_, err = local.authenticate(context.Background(), Backfill_MethodID, newTestAuthData(t, "local.example", remote_key))
+			if err != nil {
+				t.Fatalf("second authenticate: %v", err)
+			}
+			if store.stored != 1 {
+				t.Errorf("keys were stored %d times, want 1", store.stored)
+			}
+		})
+	}
+}
+
+func TestAuthenticateWithoutKeyFetcher(t *testing.T) {
+	_, remote_key := newTestServer(t, "remote.example")
+	local, _ := newTestServer(t, "local.example")
+	local.SetVerifyKeySource(&memoryKeys{servers: make(map[string]*ServerKeys)})
+
+	_, err := local.authenticate(context.Background(), Backfill_MethodID, newTestAuthData(t, "local.example", remote_key))
+	if !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("got %v, want ErrUnauthenticated", err)
+	}
+}
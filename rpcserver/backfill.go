@@ -0,0 +1,246 @@
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"capnproto.org/go/capnp/v3/flowcontrol"
+
+	"github.com/MTRNord/matrix_protobuf_fed/pdu"
+	protocol "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// The most events returned by a single Backfill call, regardless of the requested limit
+const maxBackfillLimit = 100
+
+// How many bytes of backfilled events may be in flight to the caller before we wait for it to catch up
+const backfillFlowLimit = 1 << 17
+
+// Backfill streams the requested events and the ones preceding them.
+//
+// Starting at the given event IDs the prevEvents are followed breadth-first until limit events were sent or there
+// are no more known events. A metadata chunk is sent first, followed by one pdu chunk per event.
+// Events missing from the EventStore are skipped.
+func (s RPCMatrixServer) Backfill(ctx context.Context, call protocol.MatrixFederation_backfill) error {
+	args := call.Args()
+	auth_data, err := args.Auth_data()
+	if err != nil {
+		return err
+	}
+	origin, err := s.authenticate(ctx, Backfill_MethodID, auth_data)
+	if err != nil {
+		return err
+	}
+
+	if !s.streams.begin() {
+		return ErrDraining
+	}
+	defer s.streams.end()
+	call.Go()
+
+	client := args.Callback()
+	defer client.Release()
+	client.SetFlowLimiter(flowcontrol.NewFixedLimiter(backfillFlowLimit))
+
+	room_id, err := args.RoomID()
+	if err != nil {
+		return err
+	}
+	event_ids, err := textListStrings(args.EventIDs())
+	if err != nil {
+		return err
+	}
+	limit := min(int(args.Limit()), maxBackfillLimit)
+	log.Println("Backfilling", limit, "events of", room_id, "for", origin)
+
+	err = client.Write(ctx, func(p protocol.StreamCallback_write_Params) error {
+		data, err := types.NewBackfillData(p.Segment())
+		if err != nil {
+			return err
+		}
+		err = p.SetValue(data.ToPtr())
+		if err != nil {
+			return err
+		}
+
+		metadata, err := data.NewMetadata()
+		if err != nil {
+			return err
+		}
+		err = metadata.SetOrigin(s.keys.SigningKey().ServerName())
+		if err != nil {
+			return err
+		}
+		metadata.SetOriginServerTS(time.Now().UnixMilli())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.event_store != nil {
+		err = s.backfillEvents(ctx, client, room_id, event_ids, limit)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, release := client.Done(ctx, nil)
+	defer release()
+
+	return client.WaitStreaming()
+}
+
+// backfillEvents walks the prevEvents breadth-first from the given events and streams up to limit of them
+func (s RPCMatrixServer) backfillEvents(ctx context.Context, client protocol.StreamCallback, roomID string, eventIDs []string, limit int) error {
+	queue := make([]string, 0, len(eventIDs))
+	seen := make(map[string]struct{}, len(eventIDs))
+	for _, event_id := range eventIDs {
+		if _, ok := seen[event_id]; !ok {
+			seen[event_id] = struct{}{}
+			queue = append(queue, event_id)
+		}
+	}
+
+	sent := 0
+	for len(queue) > 0 && sent < limit {
+		event_id := queue[0]
+		queue = queue[1:]
+
+		event, err := s.event_store.GetEvent(ctx, roomID, event_id)
+		if errors.Is(err, ErrEventNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		view, err := pdu.View(event)
+		if err != nil {
+			return err
+		}
+		prev_events, err := view.PrevEvents()
+		if err != nil {
+			return err
+		}
+		for _, prev_event := range prev_events {
+			if _, ok := seen[prev_event]; !ok {
+				seen[prev_event] = struct{}{}
+				queue = append(queue, prev_event)
+			}
+		}
+
+		// Blocks while the caller has too much data in flight
+		err = client.Write(ctx, func(p protocol.StreamCallback_write_Params) error {
+			data, err := types.NewBackfillData(p.Segment())
+			if err != nil {
+				return err
+			}
+			err = data.SetPdu(event)
+			if err != nil {
+				return err
+			}
+			return p.SetValue(data.ToPtr())
+		})
+		if err != nil {
+			return err
+		}
+		sent++
+	}
+	return nil
+}
+
+// BackfillReceiver receives the events of a backfill call on the requesting side.
+// Pass it as callback using protocol.StreamCallback_ServerToClient.
+//
+// Every PDU is checked like the PDUs of inbound transactions: PDUs whose signing keys could not be fetched are
+// handed on with PDUKeysUnavailable, PDUs failing the content hash check are handed on in their redacted form and malformed or
+// badly signed PDUs are dropped.
+type BackfillReceiver struct {
+	// Checks the PDUs with the keys it knows and fetches
+	server RPCMatrixServer
+	mu     sync.Mutex
+	// Server name and time from the metadata chunk
+	origin           string
+	origin_server_ts int64
+	done             chan struct{}
+	// Called for every PDU as it arrives. The PDU is only valid during the call.
+	onPDU func(pdu types.Transaction_PDU, info PDUInfo) error
+}
+
+// NewBackfillReceiver creates a BackfillReceiver that checks the signatures of the PDUs with the keys of the server.
+// An error returned by onPDU aborts the backfill.
+func (s RPCMatrixServer) NewBackfillReceiver(onPDU func(pdu types.Transaction_PDU, info PDUInfo) error) *BackfillReceiver {
+	return &BackfillReceiver{
+		server: s,
+		done:   make(chan struct{}),
+		onPDU:  onPDU,
+	}
+}
+
+func (b *BackfillReceiver) Write(ctx context.Context, call protocol.StreamCallback_write) error {
+	value, err := call.Args().Value()
+	if err != nil {
+		return err
+	}
+	data := types.BackfillData(value.Struct())
+
+	switch data.Which() {
+	case types.BackfillData_Which_metadata:
+		metadata, err := data.Metadata()
+		if err != nil {
+			return err
+		}
+		origin, err := metadata.Origin()
+		if err != nil {
+			return err
+		}
+		b.mu.Lock()
+		b.origin = origin
+		b.origin_server_ts = metadata.OriginServerTS()
+		b.mu.Unlock()
+		return nil
+	case types.BackfillData_Which_pdu:
+		event, err := data.Pdu()
+		if err != nil {
+			return err
+		}
+		event, info, err := b.server.checkPDU(ctx, event)
+		if err != nil {
+			log.Println("Dropping backfilled PDU", info.EventID, ":", err)
+			return nil
+		}
+		return b.onPDU(event, info)
+	default:
+		return fmt.Errorf("unknown backfill chunk %d", data.Which())
+	}
+}
+
+func (b *BackfillReceiver) Done(ctx context.Context, call protocol.StreamCallback_done) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	select {
+	case <-b.done:
+	default:
+		close(b.done)
+	}
+	return nil
+}
+
+// Wait blocks until the server sent all events. It returns the origin and timestamp of the metadata chunk.
+func (b *BackfillReceiver) Wait(ctx context.Context) (string, int64, error) {
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return "", 0, ctx.Err()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.origin, b.origin_server_ts, nil
+}
@@ -0,0 +1,226 @@
+package rpcserver
+
+import (
+	"fmt"
+	"sync"
+
+	capnp "capnproto.org/go/capnp/v3"
+	"capnproto.org/go/capnp/v3/schemas"
+	"capnproto.org/go/capnp/v3/std/capnp/schema"
+
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// CanonicalSorted returns the canonical form of a struct of the given type with the entries of every Map in it
+// sorted by key, so the order maps were built or received in does not change the signature.
+// The maps are sorted in a copy, message is not modified.
+//
+// The type ID is the one of the generated type, e.g. types.AuthData_TypeID. Which fields are maps is read from the
+// schema embedded in the generated code.
+func CanonicalSorted(message capnp.Struct, typeID uint64) ([]byte, error) {
+	index, err := loadSchemaIndex()
+	if err != nil {
+		return nil, err
+	}
+	if !message.IsValid() || !index.has_map[typeID] {
+		return capnp.Canonicalize(message)
+	}
+
+	msg, _, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return nil, err
+	}
+	// Setting a pointer from another message makes a deep copy
+	err = msg.SetRoot(message.ToPtr())
+	if err != nil {
+		return nil, err
+	}
+	root, err := msg.Root()
+	if err != nil {
+		return nil, err
+	}
+	copied := root.Struct()
+	err = index.sortMaps(copied, typeID)
+	if err != nil {
+		return nil, err
+	}
+	return capnp.Canonicalize(copied)
+}
+
+// schemaIndex are the struct nodes of the schema and which of them contain a Map
+type schemaIndex struct {
+	nodes   map[uint64]schema.Node
+	has_map map[uint64]bool
+}
+
+var loadSchemaIndex = sync.OnceValues(func() (*schemaIndex, error) {
+	var registry schemas.Registry
+	types.RegisterSchema(&registry)
+	// All types are declared in the same file, so one request has every node
+	data, err := registry.Find(types.Map_TypeID)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := capnp.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	request, err := schema.ReadRootCodeGeneratorRequest(msg)
+	if err != nil {
+		return nil, err
+	}
+	node_list, err := request.Nodes()
+	if err != nil {
+		return nil, err
+	}
+
+	index := &schemaIndex{
+		nodes:   make(map[uint64]schema.Node, node_list.Len()),
+		has_map: map[uint64]bool{types.Map_TypeID: true},
+	}
+	for i := 0; i < node_list.Len(); i++ {
+		node := node_list.At(i)
+		if node.Which() == schema.Node_Which_structNode {
+			index.nodes[node.Id()] = node
+		}
+	}
+
+	// Repeat until no struct is newly found to contain a map, as structs can refer to each other in any order
+	for changed := true; changed; {
+		changed = false
+		for id := range index.nodes {
+			if index.has_map[id] {
+				continue
+			}
+			has_map, err := index.fieldsHaveMap(id)
+			if err != nil {
+				return nil, err
+			}
+			if has_map {
+				index.has_map[id] = true
+				changed = true
+			}
+		}
+	}
+	return index, nil
+})
+
+// fieldsHaveMap reports whether a field of the struct is known to contain a Map
+func (x *schemaIndex) fieldsHaveMap(id uint64) (bool, error) {
+	fields, err := x.nodes[id].StructNode().Fields()
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.At(i)
+		switch field.Which() {
+		case schema.Field_Which_group:
+			if x.has_map[field.Group().TypeId()] {
+				return true, nil
+			}
+		case schema.Field_Which_slot:
+			field_type, err := field.Slot().Type()
+			if err != nil {
+				return false, err
+			}
+			has_map, err := x.typeHasMap(field_type)
+			if err != nil || has_map {
+				return has_map, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// typeHasMap reports whether a struct or list of structs is known to contain a Map
+func (x *schemaIndex) typeHasMap(t schema.Type) (bool, error) {
+	switch t.Which() {
+	case schema.Type_Which_structType:
+		return x.has_map[t.StructType().TypeId()], nil
+	case schema.Type_Which_list:
+		element, err := t.List().ElementType()
+		if err != nil {
+			return false, err
+		}
+		return x.typeHasMap(element)
+	}
+	return false, nil
+}
+
+// sortMaps sorts the maps in the struct of the given type in place. Of a union only the set member is visited.
+func (x *schemaIndex) sortMaps(s capnp.Struct, id uint64) error {
+	if id == types.Map_TypeID {
+		// The values are not visited, no map in the schema has values that contain maps
+		m := types.Map(s)
+		wrapper, err := FromMap(&m, 0)
+		if err != nil {
+			return err
+		}
+		return wrapper.Sort()
+	}
+
+	node, ok := x.nodes[id]
+	if !ok {
+		return fmt.Errorf("unknown struct type %#x", id)
+	}
+	struct_node := node.StructNode()
+	fields, err := struct_node.Fields()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.At(i)
+		if field.DiscriminantValue() != schema.Field_noDiscriminant &&
+			s.Uint16(capnp.DataOffset(struct_node.DiscriminantOffset()*2)) != field.DiscriminantValue() {
+			continue
+		}
+		switch field.Which() {
+		case schema.Field_Which_group:
+			if x.has_map[field.Group().TypeId()] {
+				err = x.sortMaps(s, field.Group().TypeId())
+			}
+		case schema.Field_Which_slot:
+			var field_type schema.Type
+			field_type, err = field.Slot().Type()
+			if err == nil {
+				err = x.sortPointer(s, uint16(field.Slot().Offset()), field_type)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortPointer sorts the maps in the struct or list of structs a pointer field of s points to
+func (x *schemaIndex) sortPointer(s capnp.Struct, pointer uint16, t schema.Type) error {
+	has_map, err := x.typeHasMap(t)
+	if err != nil || !has_map {
+		return err
+	}
+	ptr, err := s.Ptr(pointer)
+	if err != nil || !ptr.IsValid() {
+		return err
+	}
+
+	if t.Which() == schema.Type_Which_structType {
+		return x.sortMaps(ptr.Struct(), t.StructType().TypeId())
+	}
+	element, err := t.List().ElementType()
+	if err != nil {
+		return err
+	}
+	// Lists of lists do not occur in the schema, so the elements are structs
+	if element.Which() != schema.Type_Which_structType {
+		return fmt.Errorf("unsupported list of %v containing maps", element.Which())
+	}
+	list := ptr.List()
+	for i := 0; i < list.Len(); i++ {
+		err = x.sortMaps(list.Struct(i), element.StructType().TypeId())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"net"
+	"sync"
+
+	capnp "capnproto.org/go/capnp/v3"
+	"capnproto.org/go/capnp/v3/rpc"
+)
+
+// ErrServerClosed is returned by Serve after Shutdown has been called.
+var ErrServerClosed = errors.New("rpcserver: server closed")
+
+// A Drainer is able to wait for its in-flight work to finish before the connections get closed.
+type Drainer interface {
+	Drain(ctx context.Context) error
+}
+
+// ConnManager serves a bootstrap capability to every accepted connection.
+// Each rpc.Conn runs in its own goroutine and is tracked until the peer disconnects or the manager shuts down.
+type ConnManager struct {
+	boot    capnp.Client
+	drainer Drainer
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*rpc.Conn]struct{}
+	shutdown  bool
+	wg        sync.WaitGroup
+}
+
+// NewConnManager creates a new ConnManager.
+//
+// The manager takes ownership of boot and releases it once Shutdown has closed all connections.
+// drainer may be nil if there is no in-flight work to wait for on shutdown.
+func NewConnManager(boot capnp.Client, drainer Drainer) *ConnManager {
+	return &ConnManager{
+		boot:      boot,
+		drainer:   drainer,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[*rpc.Conn]struct{}),
+	}
+}
+
+// Serve accepts incoming connections on the listener and serves the bootstrap capability on each of them.
+//
+// Serve always returns a non-nil error. After Shutdown has been called the returned error is ErrServerClosed.
+func (m *ConnManager) Serve(lis net.Listener) error {
+	if !m.boot.IsValid() {
+		return errors.New("bootstrap client is not valid")
+	}
+
+	if !m.trackListener(lis) {
+		lis.Close()
+		return ErrServerClosed
+	}
+	defer m.untrackListener(lis)
+
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if m.isShutdown() {
+				return ErrServerClosed
+			}
+			return err
+		}
+		m.serveConn(conn)
+	}
+}
+
+// ActiveConns returns the number of currently open connections.
+func (m *ConnManager) ActiveConns() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.conns)
+}
+
+// Shutdown gracefully shuts the manager down.
+//
+// New connections are refused, in-flight streams are drained via the Drainer,
+// and afterwards all listeners and remaining connections are closed.
+// If ctx expires before the connections are gone the context error is returned.
+func (m *ConnManager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if m.shutdown {
+		m.mu.Unlock()
+		return ErrServerClosed
+	}
+	m.shutdown = true
+	m.mu.Unlock()
+
+	var drainErr error
+	if m.drainer != nil {
+		drainErr = m.drainer.Drain(ctx)
+	}
+
+	m.mu.Lock()
+	for lis := range m.listeners {
+		lis.Close()
+	}
+	for rpc_conn := range m.conns {
+		rpc_conn.Close()
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		m.boot.Release()
+		return drainErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *ConnManager) serveConn(conn net.Conn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.shutdown {
+		conn.Close()
+		return
+	}
+
+	// the RPC connection takes ownership of the bootstrap interface and will release it when the connection
+	// exits, so use AddRef to avoid releasing the provided bootstrap client capability.
+	opts := rpc.Options{
+		BootstrapClient: m.boot.AddRef(),
+		Logger:          slog.Default(),
+	}
+	// The rpc connection owns the transport which in turn owns the net.Conn. Closing it closes all of them.
+	rpc_conn := rpc.NewConn(rpc.NewStreamTransport(conn), &opts)
+	m.conns[rpc_conn] = struct{}{}
+	m.wg.Add(1)
+
+	go func() {
+		defer m.wg.Done()
+		<-rpc_conn.Done()
+
+		m.mu.Lock()
+		delete(m.conns, rpc_conn)
+		m.mu.Unlock()
+
+		if err := rpc_conn.Close(); err != nil && !errors.Is(err, rpc.ErrConnClosed) {
+			log.Println("Error closing connection from", conn.RemoteAddr(), ":", err)
+		}
+	}()
+}
+
+func (m *ConnManager) trackListener(lis net.Listener) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.shutdown {
+		return false
+	}
+	m.listeners[lis] = struct{}{}
+	return true
+}
+
+func (m *ConnManager) untrackListener(lis net.Listener) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.listeners, lis)
+}
+
+func (m *ConnManager) isShutdown() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.shutdown
+}
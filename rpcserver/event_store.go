@@ -0,0 +1,39 @@
+package rpcserver
+
+import (
+	"context"
+	"errors"
+
+	capnp "capnproto.org/go/capnp/v3"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// ErrEventNotFound is returned by an EventStore if it does not know the requested event.
+var ErrEventNotFound = errors.New("event not found")
+
+// An EventStore gives access to the PDUs of rooms.
+type EventStore interface {
+	// GetEvent returns the PDU with the event ID in the room or ErrEventNotFound.
+	// The PDU has to stay valid until the next call.
+	GetEvent(ctx context.Context, roomID string, eventID string) (types.Transaction_PDU, error)
+}
+
+// SetEventStore sets where the PDUs served via Backfill come from
+func (s *RPCMatrixServer) SetEventStore(store EventStore) {
+	s.event_store = store
+}
+
+func textListStrings(list capnp.TextList, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	strings := make([]string, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		s, err := list.At(i)
+		if err != nil {
+			return nil, err
+		}
+		strings = append(strings, s)
+	}
+	return strings, nil
+}
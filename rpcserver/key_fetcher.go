@@ -0,0 +1,252 @@
+package rpcserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	capnp "capnproto.org/go/capnp/v3"
+
+	protocol "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// ErrInvalidKeysStream is wrapped by errors returned when the chunks of a getKeys stream are out of order.
+var ErrInvalidKeysStream = errors.New("invalid server keys stream")
+
+// A KeyFetcher fetches the keys of other servers whose signatures can not be checked with the keys we know.
+type KeyFetcher interface {
+	// FetchKeys fetches the current keys of the server and makes them available to the VerifyKeySource.
+	FetchKeys(ctx context.Context, serverName string) error
+}
+
+// SetKeyFetcher sets how unknown keys of other servers are fetched when checking the signatures of requests and PDUs.
+// Without a fetcher only the keys of the VerifyKeySource are used.
+func (s *RPCMatrixServer) SetKeyFetcher(fetcher KeyFetcher) {
+	s.key_fetcher = fetcher
+}
+
+// FetchServerKeys asks the server behind client for its own keys via getKeys.
+// Only keys carrying valid self-signatures are returned.
+func FetchServerKeys(ctx context.Context, client protocol.MatrixFederation) ([]*ServerKeys, error) {
+	receiver := NewServerKeysReceiver()
+	callback := protocol.StreamCallback_ServerToClient(receiver)
+	defer callback.Release()
+
+	future, release := client.GetKeys(ctx, func(p protocol.MatrixFederation_getKeys_Params) error {
+		return p.SetCallback(callback.AddRef())
+	})
+	defer release()
+	_, err := future.Struct()
+	if err != nil {
+		return nil, err
+	}
+	return receiver.Wait(ctx)
+}
+
+// ServerKeysReceiver collects the keys streamed by getKeys on the requesting side.
+// Pass it as callback using protocol.StreamCallback_ServerToClient.
+//
+// Every batch of a metadata chunk followed by a verify_keys chunk and an optional old_verify_keys chunk becomes one
+// ServerKeys. Each chunk has to be signed by one of the verify keys of the batch, batches failing this are dropped.
+// Signatures of notary servers are kept but not checked.
+type ServerKeysReceiver struct {
+	mu sync.Mutex
+	// The batch that is being received
+	current *serverKeysBatch
+	keys    []*ServerKeys
+	done    chan struct{}
+}
+
+// serverKeysBatch keeps the canonical form of the chunks until the verify keys to check them with are known
+type serverKeysBatch struct {
+	keys            *ServerKeys
+	metadata        []byte
+	verify_keys     []byte
+	old_verify_keys []byte
+}
+
+// NewServerKeysReceiver creates an empty ServerKeysReceiver
+func NewServerKeysReceiver() *ServerKeysReceiver {
+	return &ServerKeysReceiver{
+		done: make(chan struct{}),
+	}
+}
+
+func (r *ServerKeysReceiver) Write(ctx context.Context, call protocol.StreamCallback_write) error {
+	value, err := call.Args().Value()
+	if err != nil {
+		return err
+	}
+	response := types.ServerKeysResponse(value.Struct())
+	signatures, err := response.Signatures()
+	if err != nil {
+		return err
+	}
+	signatures_builder, err := SignatureListBuilderFrom(signatures)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch response.Which() {
+	case types.ServerKeysResponse_Which_metadata:
+		r.finishBatch()
+
+		metadata, err := response.Metadata()
+		if err != nil {
+			return err
+		}
+		server_name, err := metadata.ServerName()
+		if err != nil {
+			return err
+		}
+		metadata_bytes, err := CanonicalSorted(capnp.Struct(metadata), types.ServerKeysResponse_Metadata_TypeID)
+		if err != nil {
+			return err
+		}
+		r.current = &serverKeysBatch{
+			keys: &ServerKeys{
+				ServerName:         server_name,
+				ValidUntilTS:       metadata.ValidUntilTS(),
+				MetadataSignatures: signatures_builder.Signatures(),
+			},
+			metadata: metadata_bytes,
+		}
+		return nil
+	case types.ServerKeysResponse_Which_verifyKeys:
+		if r.current == nil || r.current.verify_keys != nil {
+			return fmt.Errorf("%w: verify_keys chunk without metadata", ErrInvalidKeysStream)
+		}
+		verify_keys, err := response.VerifyKeys()
+		if err != nil {
+			return err
+		}
+		r.current.verify_keys, err = CanonicalizeMap(verify_keys)
+		if err != nil {
+			return err
+		}
+		r.current.keys.VerifyKeysSignatures = signatures_builder.Signatures()
+		r.current.keys.VerifyKeys = make(map[KeyID]ed25519.PublicKey)
+		verify_keys_map, err := FromMap(&verify_keys, 0)
+		if err != nil {
+			return err
+		}
+		return verify_keys_map.Range(func(key capnp.Ptr, value capnp.Ptr) bool {
+			// The message is released after the call so we have to copy the key
+			r.current.keys.VerifyKeys[KeyID(key.Text())] = ed25519.PublicKey(bytes.Clone(value.Data()))
+			return true
+		})
+	case types.ServerKeysResponse_Which_oldVerifyKeys:
+		if r.current == nil || r.current.verify_keys == nil || r.current.old_verify_keys != nil {
+			return fmt.Errorf("%w: old_verify_keys chunk without verify_keys", ErrInvalidKeysStream)
+		}
+		old_verify_keys, err := response.OldVerifyKeys()
+		if err != nil {
+			return err
+		}
+		r.current.old_verify_keys, err = CanonicalizeMap(old_verify_keys)
+		if err != nil {
+			return err
+		}
+		r.current.keys.OldVerifyKeysSignatures = signatures_builder.Signatures()
+		r.current.keys.OldVerifyKeys = make(map[KeyID]OldVerifyKey)
+		old_verify_keys_map, err := FromMap(&old_verify_keys, 0)
+		if err != nil {
+			return err
+		}
+		var key_err error
+		err = old_verify_keys_map.Range(func(key capnp.Ptr, value capnp.Ptr) bool {
+			old_verify_key := types.ServerKeysResponse_OldVerifyKey(value.Struct())
+			var public_key []byte
+			public_key, key_err = old_verify_key.Key()
+			if key_err != nil {
+				return false
+			}
+			r.current.keys.OldVerifyKeys[KeyID(key.Text())] = OldVerifyKey{
+				ExpiredTS: old_verify_key.ExpiredTS(),
+				Key:       ed25519.PublicKey(bytes.Clone(public_key)),
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		return key_err
+	default:
+		return fmt.Errorf("%w: unknown chunk %d", ErrInvalidKeysStream, response.Which())
+	}
+}
+
+// finishBatch checks the self-signatures of the current batch and keeps its keys if they are valid.
+// The caller has to hold the lock.
+func (r *ServerKeysReceiver) finishBatch() {
+	batch := r.current
+	r.current = nil
+	if batch == nil {
+		return
+	}
+	if batch.verify_keys == nil {
+		log.Println("Dropping server keys of", batch.keys.ServerName, "without verify_keys")
+		return
+	}
+
+	err := checkSelfSigned(batch.keys, batch.metadata, batch.keys.MetadataSignatures)
+	if err == nil {
+		err = checkSelfSigned(batch.keys, batch.verify_keys, batch.keys.VerifyKeysSignatures)
+	}
+	if err == nil && batch.old_verify_keys != nil {
+		err = checkSelfSigned(batch.keys, batch.old_verify_keys, batch.keys.OldVerifyKeysSignatures)
+	}
+	if err != nil {
+		log.Println("Dropping server keys of", batch.keys.ServerName, ":", err)
+		return
+	}
+	r.keys = append(r.keys, batch.keys)
+}
+
+// checkSelfSigned checks that the message carries a valid signature of the server made with one of its verify keys
+func checkSelfSigned(keys *ServerKeys, message []byte, signatures Signatures) error {
+	for key_id, signature := range signatures[keys.ServerName] {
+		if !strings.HasPrefix(string(key_id), "ed25519:") {
+			continue
+		}
+		public_key, ok := keys.VerifyKeys[key_id]
+		if ok && ed25519.Verify(public_key, message, signature) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no valid signature of %s with one of its verify keys", keys.ServerName)
+}
+
+func (r *ServerKeysReceiver) Done(ctx context.Context, call protocol.StreamCallback_done) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	select {
+	case <-r.done:
+	default:
+		r.finishBatch()
+		close(r.done)
+	}
+	return nil
+}
+
+// Wait blocks until the server sent all keys and returns the ones with valid self-signatures
+func (r *ServerKeysReceiver) Wait(ctx context.Context) ([]*ServerKeys, error) {
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.keys, nil
+}
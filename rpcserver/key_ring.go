@@ -0,0 +1,299 @@
+package rpcserver
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OldVerifyKey is a key the server used to use and when it stopped using it
+type OldVerifyKey struct {
+	// POSIX timestamp in milliseconds for when this key expired
+	ExpiredTS int64
+	Key       ed25519.PublicKey
+}
+
+// KeyRing holds our own signing keys and the retired ones.
+// It can be modified at runtime to rotate keys without restarting the server.
+type KeyRing struct {
+	mu sync.RWMutex
+	// The key used to sign our responses
+	active SigningKeyWrapper
+	// All keys we publish as verify keys. This includes active.
+	verify_keys []SigningKeyWrapper
+	// Retired keys we publish as old verify keys
+	old_verify_keys map[KeyID]OldVerifyKey
+	// File the retired keys are saved to when they change, see SetOldVerifyKeysPath
+	old_verify_keys_path string
+}
+
+// NewKeyRing creates a KeyRing. The first key is used for signing, all of them are published as verify keys.
+func NewKeyRing(signingKeys []SigningKeyWrapper) (*KeyRing, error) {
+	if len(signingKeys) == 0 {
+		return nil, ErrNoSigningKeys
+	}
+
+	return &KeyRing{
+		active:          signingKeys[0],
+		verify_keys:     slices.Clone(signingKeys),
+		old_verify_keys: make(map[KeyID]OldVerifyKey),
+	}, nil
+}
+
+// SigningKey returns the key currently used for signing
+func (r *KeyRing) SigningKey() SigningKeyWrapper {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active
+}
+
+// VerifyKeys returns all keys that are currently valid
+func (r *KeyRing) VerifyKeys() []SigningKeyWrapper {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.verify_keys)
+}
+
+// OldVerifyKeys returns the retired keys
+func (r *KeyRing) OldVerifyKeys() map[KeyID]OldVerifyKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return maps.Clone(r.old_verify_keys)
+}
+
+// SetOldVerifyKeysPath sets the file the retired keys are saved to whenever Retire or Rotate changes them, so they
+// are still published after a restart. The file has the format read by LoadOldVerifyKeys.
+func (r *KeyRing) SetOldVerifyKeysPath(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.old_verify_keys_path = path
+}
+
+// AddOldVerifyKey adds a retired key, e.g. from configuration.
+// A key that is still in use can not be added as retired.
+func (r *KeyRing) AddOldVerifyKey(keyID KeyID, key OldVerifyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.indexOf(keyID) >= 0 {
+		return fmt.Errorf("key %s is still in use", keyID)
+	}
+	r.old_verify_keys[keyID] = key
+	return nil
+}
+
+// Retire stops publishing the key as verify key and publishes it as old verify key with the given expiry.
+// The active signing key can not be retired, use Rotate instead. If the retired keys can not be saved, the key ring
+// is left unchanged.
+func (r *KeyRing) Retire(keyID KeyID, expiredTS int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if keyID == r.active.KeyID() {
+		return fmt.Errorf("key %s is the active signing key", keyID)
+	}
+	index := r.indexOf(keyID)
+	if index < 0 {
+		return fmt.Errorf("key %s is not a verify key", keyID)
+	}
+
+	key := r.verify_keys[index]
+	old_verify_keys := maps.Clone(r.old_verify_keys)
+	old_verify_keys[keyID] = OldVerifyKey{
+		ExpiredTS: expiredTS,
+		Key:       key.PublicKey(),
+	}
+	// The key ring only changes once the retired key is saved, so it is not lost on a restart
+	err := r.saveOldVerifyKeys(old_verify_keys)
+	if err != nil {
+		return err
+	}
+	r.old_verify_keys = old_verify_keys
+	r.verify_keys = slices.Delete(r.verify_keys, index, index+1)
+	return nil
+}
+
+// Rotate replaces the signing keys with the given ones.
+// The first key becomes the active signing key. Keys that are no longer part of the given keys are retired
+// with the current time as expiry, so signatures made with them can still be checked by other servers.
+// If the retired keys can not be saved, the key ring is left unchanged.
+func (r *KeyRing) Rotate(signingKeys []SigningKeyWrapper) error {
+	if len(signingKeys) == 0 {
+		return ErrNoSigningKeys
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	old_verify_keys := maps.Clone(r.old_verify_keys)
+	for _, verify_key := range r.verify_keys {
+		still_used := slices.ContainsFunc(signingKeys, func(k SigningKeyWrapper) bool {
+			return k.KeyID() == verify_key.KeyID()
+		})
+		if !still_used {
+			old_verify_keys[verify_key.KeyID()] = OldVerifyKey{
+				ExpiredTS: now,
+				Key:       verify_key.PublicKey(),
+			}
+		}
+	}
+	// A key that comes back into use is no longer retired
+	for _, signing_key := range signingKeys {
+		delete(old_verify_keys, signing_key.KeyID())
+	}
+
+	// The rotation only takes effect once the retired keys are saved, so they are not lost on a restart
+	err := r.saveOldVerifyKeys(old_verify_keys)
+	if err != nil {
+		return err
+	}
+	r.old_verify_keys = old_verify_keys
+	r.active = signingKeys[0]
+	r.verify_keys = slices.Clone(signingKeys)
+	return nil
+}
+
+// saveOldVerifyKeys writes the retired keys to the file set with SetOldVerifyKeysPath. The caller has to hold the lock.
+func (r *KeyRing) saveOldVerifyKeys(keys map[KeyID]OldVerifyKey) error {
+	if r.old_verify_keys_path == "" {
+		return nil
+	}
+	err := SaveOldVerifyKeys(r.old_verify_keys_path, keys)
+	if err != nil {
+		return fmt.Errorf("saving old verify keys: %w", err)
+	}
+	return nil
+}
+
+func (r *KeyRing) indexOf(keyID KeyID) int {
+	return slices.IndexFunc(r.verify_keys, func(k SigningKeyWrapper) bool {
+		return k.KeyID() == keyID
+	})
+}
+
+// LoadOldVerifyKeys reads retired keys from a file. See ReadOldVerifyKeys for the format.
+func LoadOldVerifyKeys(path string) (map[KeyID]OldVerifyKey, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	keys, err := ReadOldVerifyKeys(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// SaveOldVerifyKeys replaces the file with the retired keys. See ReadOldVerifyKeys for the format.
+func SaveOldVerifyKeys(path string, keys map[KeyID]OldVerifyKey) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = WriteOldVerifyKeys(tmp, keys)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// WriteOldVerifyKeys writes retired keys sorted by key ID in the format read by ReadOldVerifyKeys.
+func WriteOldVerifyKeys(w io.Writer, keys map[KeyID]OldVerifyKey) error {
+	key_ids := make([]KeyID, 0, len(keys))
+	for key_id := range keys {
+		key_ids = append(key_ids, key_id)
+	}
+	slices.Sort(key_ids)
+
+	writer := bufio.NewWriter(w)
+	for _, key_id := range key_ids {
+		key := keys[key_id]
+		_, err := fmt.Fprintf(writer, "%s %s %d\n", key_id, base64.RawStdEncoding.EncodeToString(key.Key), key.ExpiredTS)
+		if err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// ReadOldVerifyKeys reads retired keys. This carries the same data as the old_signing_keys option of Synapse.
+//
+// Each line contains one key as "<key id> <base64 public key> <expired_ts>", e.g.
+//
+//	ed25519:old1 l8Hft5qXKn1vfHrg3p4+W8gELQVo8N13JkluMfmn2sQ 1576767829750
+//
+// Empty lines are ignored.
+func ReadOldVerifyKeys(r io.Reader) (map[KeyID]OldVerifyKey, error) {
+	keys := make(map[KeyID]OldVerifyKey)
+
+	scanner := bufio.NewScanner(r)
+	line_number := 0
+	for scanner.Scan() {
+		line_number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		key_id, key, err := parseOldVerifyKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line_number, err)
+		}
+		if _, ok := keys[key_id]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key ID %q", line_number, key_id)
+		}
+		keys[key_id] = key
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func parseOldVerifyKey(line string) (KeyID, OldVerifyKey, error) {
+	parts := strings.Fields(line)
+	if len(parts) != 3 {
+		return "", OldVerifyKey{}, fmt.Errorf("expected 3 fields but got %d", len(parts))
+	}
+
+	algorithm, version, found := strings.Cut(parts[0], ":")
+	if !found || algorithm != "ed25519" || !keyVersionRegex.MatchString(version) {
+		return "", OldVerifyKey{}, fmt.Errorf("invalid key ID %q", parts[0])
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return "", OldVerifyKey{}, fmt.Errorf("invalid public key: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return "", OldVerifyKey{}, fmt.Errorf("invalid public key length %d, expected %d", len(key), ed25519.PublicKeySize)
+	}
+
+	expired_ts, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", OldVerifyKey{}, fmt.Errorf("invalid expired_ts: %w", err)
+	}
+
+	return KeyID(parts[0]), OldVerifyKey{ExpiredTS: expired_ts, Key: key}, nil
+}
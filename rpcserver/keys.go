@@ -0,0 +1,294 @@
+package rpcserver
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"log"
+	"time"
+
+	capnp "capnproto.org/go/capnp/v3"
+
+	protocol "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// How long our own key responses are valid for
+const ownKeysValidity = time.Hour * 24
+
+// Signatures maps server names to key IDs to signatures
+type Signatures map[string]map[KeyID][]byte
+
+// ServerKeys are the published keys of a server.
+// This is the data of a batch of ServerKeysResponse chunks for one server.
+type ServerKeys struct {
+	ServerName string
+	// POSIX timestamp in milliseconds until which the keys are valid
+	ValidUntilTS int64
+	VerifyKeys   map[KeyID]ed25519.PublicKey
+	// Keys the server used to use
+	OldVerifyKeys map[KeyID]OldVerifyKey
+
+	// The signatures of the metadata chunk as received from the server
+	MetadataSignatures Signatures
+	// The signatures of the verify_keys chunk as received from the server
+	VerifyKeysSignatures Signatures
+	// The signatures of the old_verify_keys chunk as received from the server
+	OldVerifyKeysSignatures Signatures
+}
+
+// HasKey returns true if the server published a current or old key with the given ID
+func (k *ServerKeys) HasKey(keyID KeyID) bool {
+	if _, ok := k.VerifyKeys[keyID]; ok {
+		return true
+	}
+	_, ok := k.OldVerifyKeys[keyID]
+	return ok
+}
+
+// A KeyProvider gives access to the keys of other servers we have fetched before.
+// It is used to answer notary requests in GetKeys.
+type KeyProvider interface {
+	// ServerKeys returns the last known keys of the server or ErrKeyNotFound if there are none.
+	ServerKeys(serverName string) (*ServerKeys, error)
+}
+
+// ownKeys returns our own keys in the same form as the ones of other servers.
+// The signatures are left empty as we sign the chunks when sending them.
+func (s RPCMatrixServer) ownKeys() *ServerKeys {
+	signing_keys := s.keys.VerifyKeys()
+	verify_keys := make(map[KeyID]ed25519.PublicKey, len(signing_keys))
+	for _, verify_key := range signing_keys {
+		verify_keys[verify_key.KeyID()] = verify_key.PublicKey()
+	}
+
+	return &ServerKeys{
+		ServerName:    s.keys.SigningKey().ServerName(),
+		ValidUntilTS:  time.Now().Add(ownKeysValidity).UnixMilli(),
+		VerifyKeys:    verify_keys,
+		OldVerifyKeys: s.keys.OldVerifyKeys(),
+	}
+}
+
+// queryKeys resolves a notary query of the form Map(Text, Map(Text, QueryCriteria)) to the keys we can serve.
+// Servers we have no keys for, or whose keys do not fulfill the criteria, are left out.
+func (s RPCMatrixServer) queryKeys(query types.Map) ([]*ServerKeys, error) {
+	var result []*ServerKeys
+	var lookup_err error
+
+	servers, err := FromMap(&query, 0)
+	if err != nil {
+		return nil, err
+	}
+	err = servers.Range(func(key capnp.Ptr, value capnp.Ptr) bool {
+		server_name := key.Text()
+
+		criteria := types.Map(value.Struct())
+		criteria_map, err := FromMap(&criteria, 0)
+		if err != nil {
+			lookup_err = err
+			return false
+		}
+		key_ids := make(map[KeyID]int64)
+		lookup_err = criteria_map.Range(func(key capnp.Ptr, value capnp.Ptr) bool {
+			key_ids[KeyID(key.Text())] = types.QueryCriteria(value.Struct()).MinimumValidUntilTS()
+			return true
+		})
+		if lookup_err != nil {
+			return false
+		}
+
+		var keys *ServerKeys
+		if server_name == s.keys.SigningKey().ServerName() {
+			keys = s.ownKeys()
+		} else if s.key_provider != nil {
+			keys, lookup_err = s.key_provider.ServerKeys(server_name)
+			if errors.Is(lookup_err, ErrKeyNotFound) {
+				lookup_err = nil
+				return true
+			}
+			if lookup_err != nil {
+				return false
+			}
+		} else {
+			return true
+		}
+
+		if keysMatchCriteria(keys, key_ids) {
+			result = append(result, keys)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if lookup_err != nil {
+		return nil, lookup_err
+	}
+
+	return result, nil
+}
+
+// keysMatchCriteria checks if the keys contain the requested key IDs and are valid long enough.
+// If no key IDs were requested all keys are of interest and have to be valid until now.
+// A minimumValidUntilTS of 0 means the current time as specified for QueryCriteria.
+func keysMatchCriteria(keys *ServerKeys, criteria map[KeyID]int64) bool {
+	now := time.Now().UnixMilli()
+	if len(criteria) == 0 {
+		return keys.ValidUntilTS >= now
+	}
+
+	for key_id, minimum_valid_until_ts := range criteria {
+		if minimum_valid_until_ts == 0 {
+			minimum_valid_until_ts = now
+		}
+		if keys.HasKey(key_id) && keys.ValidUntilTS >= minimum_valid_until_ts {
+			return true
+		}
+	}
+	return false
+}
+
+// writeServerKeys streams the keys of one server as a metadata chunk followed by a verify_keys chunk
+// and, if the server has retired keys, an old_verify_keys chunk.
+// Every chunk is signed by us in addition to the signatures of the original server.
+func (s RPCMatrixServer) writeServerKeys(ctx context.Context, client protocol.StreamCallback, keys *ServerKeys) error {
+	err := client.Write(ctx, func(p protocol.StreamCallback_write_Params) error {
+		log.Println("Sending server keys metadata response for", keys.ServerName)
+
+		response, err := types.NewServerKeysResponse(p.Segment())
+		if err != nil {
+			return err
+		}
+		err = p.SetValue(response.ToPtr())
+		if err != nil {
+			return err
+		}
+
+		metadata, err := response.NewMetadata()
+		if err != nil {
+			return err
+		}
+		err = metadata.SetServerName(keys.ServerName)
+		if err != nil {
+			return err
+		}
+		metadata.SetValidUntilTS(keys.ValidUntilTS)
+
+		return s.signServerKeysResponse(response, capnp.Struct(metadata), types.ServerKeysResponse_Metadata_TypeID, keys.MetadataSignatures)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = client.Write(ctx, func(p protocol.StreamCallback_write_Params) error {
+		log.Println("Sending server keys verify_keys response for", keys.ServerName)
+
+		response, err := types.NewServerKeysResponse(p.Segment())
+		if err != nil {
+			return err
+		}
+		err = p.SetValue(response.ToPtr())
+		if err != nil {
+			return err
+		}
+
+		verify_keys_raw, err := response.NewVerifyKeys()
+		if err != nil {
+			return err
+		}
+		verify_keys, err := FromMap(&verify_keys_raw, int32(len(keys.VerifyKeys)))
+		if err != nil {
+			return err
+		}
+		for key_id, public_key := range keys.VerifyKeys {
+			key, err := capnp.NewText(verify_keys.Segment(), string(key_id))
+			if err != nil {
+				return err
+			}
+
+			data, err := capnp.NewData(verify_keys.Segment(), public_key)
+			if err != nil {
+				return err
+			}
+			err = verify_keys.AddEntry(key.ToPtr(), data.ToPtr())
+			if err != nil {
+				return err
+			}
+		}
+		return s.signServerKeysMap(response, verify_keys, keys.VerifyKeysSignatures)
+	})
+	if err != nil || len(keys.OldVerifyKeys) == 0 {
+		return err
+	}
+
+	return client.Write(ctx, func(p protocol.StreamCallback_write_Params) error {
+		log.Println("Sending server keys old_verify_keys response for", keys.ServerName)
+
+		response, err := types.NewServerKeysResponse(p.Segment())
+		if err != nil {
+			return err
+		}
+		err = p.SetValue(response.ToPtr())
+		if err != nil {
+			return err
+		}
+
+		old_verify_keys_raw, err := response.NewOldVerifyKeys()
+		if err != nil {
+			return err
+		}
+		old_verify_keys, err := FromMap(&old_verify_keys_raw, int32(len(keys.OldVerifyKeys)))
+		if err != nil {
+			return err
+		}
+		for key_id, old_verify_key := range keys.OldVerifyKeys {
+			key, err := capnp.NewText(old_verify_keys.Segment(), string(key_id))
+			if err != nil {
+				return err
+			}
+
+			value, err := types.NewServerKeysResponse_OldVerifyKey(old_verify_keys.Segment())
+			if err != nil {
+				return err
+			}
+			value.SetExpiredTS(old_verify_key.ExpiredTS)
+			err = value.SetKey(old_verify_key.Key)
+			if err != nil {
+				return err
+			}
+
+			err = old_verify_keys.AddEntry(key.ToPtr(), value.ToPtr())
+			if err != nil {
+				return err
+			}
+		}
+		return s.signServerKeysMap(response, old_verify_keys, keys.OldVerifyKeysSignatures)
+	})
+}
+
+// signServerKeysMap sorts and truncates the keys map of the response and signs it with signServerKeysResponse
+func (s RPCMatrixServer) signServerKeysMap(response types.ServerKeysResponse, keys *Map[capnp.Ptr, capnp.Ptr], existing Signatures) error {
+	err := keys.prepare()
+	if err != nil {
+		return err
+	}
+	return s.signServerKeysResponse(response, capnp.Struct(*keys.internalMap), types.Map_TypeID, existing)
+}
+
+// signServerKeysResponse sets the existing signatures plus our own signature of the message, a struct of the given
+// type, on the response
+func (s RPCMatrixServer) signServerKeysResponse(response types.ServerKeysResponse, message capnp.Struct, typeID uint64, existing Signatures) error {
+	signatures := NewSignatureListBuilder()
+	signatures.AddAll(existing)
+	signing_key := s.keys.SigningKey()
+	err := signatures.Sign(signing_key.ServerName(), signing_key.KeyID(), signing_key.privateKey, message, typeID)
+	if err != nil {
+		return err
+	}
+	signatures_list, err := signatures.Build(response.Segment())
+	if err != nil {
+		return err
+	}
+	return response.SetSignatures(signatures_list)
+}
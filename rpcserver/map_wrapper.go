@@ -1,10 +1,17 @@
 package rpcserver
 
 import (
+	"bytes"
+	"errors"
+	"slices"
+
 	capnp "capnproto.org/go/capnp/v3"
 	protocol_types "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
 )
 
+// ErrDuplicateMapKey is returned if a map contains the same key twice and therefore has no canonical form.
+var ErrDuplicateMapKey = errors.New("map contains duplicate key")
+
 /*
  * This is a wrapper for the Map capnproto type which is defined as:
  *
@@ -19,11 +26,18 @@ import (
  * }
  * ```
  *
- * Contrary to normal go maps this map has a fixed size.
+ * Capnproto lists have a fixed size. The wrapper preallocates maxSize entries and keeps track of how many of them
+ * are used. If the list is full it gets reallocated with double the size and the entries are copied over.
+ * Call Truncate before sending or canonicalizing the map to drop the unused entries.
+ *
+ * Before a map is signed its entries have to be sorted by key, otherwise two servers producing the same logical map
+ * would produce different signatures. Canonicalize sorts the map in place, CanonicalizeMap sorts a copy. Signing
+ * and verifying sort every map in a copy of the signed struct, see CanonicalSorted.
  */
 type Map[Key capnp.Ptr, Value capnp.Ptr] struct {
 	internalMap *protocol_types.Map
 	maxSize     int32
+	size        int32
 }
 
 // NewMap creates a new Map Wrapper
@@ -39,17 +53,33 @@ func NewMap[Key capnp.Ptr, Value capnp.Ptr](s *capnp.Segment, maxSize int32) (*M
 	}, nil
 }
 
-// FromMap converts a capnp map to a wrapper
-func FromMap[Key capnp.Ptr, Value capnp.Ptr](m *protocol_types.Map, maxSize int32) *Map[Key, Value] {
+// FromMap converts a capnp map to a wrapper.
+// Existing entries of the map are considered used.
+func FromMap[Key capnp.Ptr, Value capnp.Ptr](m *protocol_types.Map, maxSize int32) (*Map[Key, Value], error) {
+	size := int32(0)
+	if m.HasEntries() {
+		entries, err := m.Entries()
+		if err != nil {
+			return nil, err
+		}
+		size = int32(entries.Len())
+	}
+
 	return &Map[Key, Value]{
 		internalMap: m,
-		maxSize:     maxSize,
-	}
+		maxSize:     max(maxSize, size),
+		size:        size,
+	}, nil
 }
 
 // HasEntries returns true if the map has entries
 func (m *Map[Key, Value]) HasEntries() bool {
-	return m.internalMap.HasEntries()
+	return m.size > 0
+}
+
+// Len returns the number of used entries
+func (m *Map[Key, Value]) Len() int {
+	return int(m.size)
 }
 
 // Get a Segment of the internal map
@@ -59,54 +89,312 @@ func (m *Map[Key, Value]) Segment() *capnp.Segment {
 
 // Entries returns the entries of the map as a go map
 func (m *Map[Key, Value]) Entries() (map[Key]*Value, error) {
-	// Check if we have entries. If not we return an empty map
-	result := make(map[Key]*Value)
+	result := make(map[Key]*Value, m.size)
+	err := m.Range(func(key Key, value Value) bool {
+		result[key] = &value
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Range calls fn for every used entry in order. Iteration stops if fn returns false.
+func (m *Map[Key, Value]) Range(fn func(key Key, value Value) bool) error {
+	// Check if we have entries. If not there is nothing to do
 	if !m.HasEntries() {
-		return result, nil
+		return nil
 	}
 
 	entries, err := m.internalMap.Entries()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	for i := 0; i < entries.Len(); i++ {
+	for i := 0; i < int(m.size); i++ {
 		entry := entries.At(i)
 		key, err := entry.Key()
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		value_raw, err := entry.Value()
+		value, err := entry.Value()
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		value := Value(value_raw)
-		result[Key(key)] = &value
+		if !fn(Key(key), Value(value)) {
+			return nil
+		}
 	}
 
-	return result, nil
+	return nil
 }
 
+// GetText returns the value for a Text key. The boolean is false if the key does not exist.
+func (m *Map[Key, Value]) GetText(key string) (Value, bool, error) {
+	var zero Value
+	index, err := m.indexOfText(key)
+	if err != nil || index < 0 {
+		return zero, false, err
+	}
+
+	entries, err := m.internalMap.Entries()
+	if err != nil {
+		return zero, false, err
+	}
+	value, err := entries.At(index).Value()
+	if err != nil {
+		return zero, false, err
+	}
+	return Value(value), true, nil
+}
+
+// DeleteText removes the entry with the given Text key. The boolean is false if the key did not exist.
+func (m *Map[Key, Value]) DeleteText(key string) (bool, error) {
+	index, err := m.indexOfText(key)
+	if err != nil || index < 0 {
+		return false, err
+	}
+
+	entries, err := m.internalMap.Entries()
+	if err != nil {
+		return false, err
+	}
+
+	// Shift the following entries down by one
+	for i := index; i < int(m.size)-1; i++ {
+		err = copyEntry(entries.At(i), entries.At(i+1))
+		if err != nil {
+			return false, err
+		}
+	}
+
+	last := entries.At(int(m.size) - 1)
+	err = last.SetKey(capnp.Ptr{})
+	if err != nil {
+		return false, err
+	}
+	err = last.SetValue(capnp.Ptr{})
+	if err != nil {
+		return false, err
+	}
+	m.size--
+
+	return true, nil
+}
+
+// AddEntry appends an entry to the map and grows the underlying list if it is full.
+// It does not check for duplicate keys.
 func (m *Map[Key, Value]) AddEntry(key Key, value Value) error {
-	// Check if we have any entries
-	if !m.internalMap.HasEntries() {
-		// Allocate enough entries
-		_, err := m.internalMap.NewEntries(m.maxSize)
+	capacity := int32(0)
+	if m.internalMap.HasEntries() {
+		entries, err := m.internalMap.Entries()
+		if err != nil {
+			return err
+		}
+		capacity = int32(entries.Len())
+	}
+
+	if m.size >= capacity {
+		err := m.resize(max(m.maxSize, capacity*2, 1))
 		if err != nil {
 			return err
 		}
 	}
+
 	internalEntries, err := m.internalMap.Entries()
 	if err != nil {
 		return err
 	}
 
-	entry := internalEntries.At(internalEntries.Len() - 1)
+	entry := internalEntries.At(int(m.size))
 	err = entry.SetKey(capnp.Ptr(key))
 	if err != nil {
 		return err
 	}
-	return entry.SetValue(capnp.Ptr(value))
+	err = entry.SetValue(capnp.Ptr(value))
+	if err != nil {
+		return err
+	}
+	m.size++
+
+	return nil
+}
+
+// Truncate drops the unused entries of the underlying list.
+// This has to be called before the map is sent or canonicalized as unused entries are part of the message otherwise.
+func (m *Map[Key, Value]) Truncate() error {
+	if !m.internalMap.HasEntries() {
+		return nil
+	}
+
+	entries, err := m.internalMap.Entries()
+	if err != nil {
+		return err
+	}
+	if int32(entries.Len()) == m.size {
+		return nil
+	}
+
+	return m.resize(m.size)
+}
+
+// resize reallocates the entries list with the given capacity and copies the used entries over
+func (m *Map[Key, Value]) resize(capacity int32) error {
+	new_entries, err := protocol_types.NewMap_Entry_List(m.Segment(), capacity)
+	if err != nil {
+		return err
+	}
+
+	if m.size > 0 {
+		old_entries, err := m.internalMap.Entries()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < int(m.size); i++ {
+			err = copyEntry(new_entries.At(i), old_entries.At(i))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	m.maxSize = max(m.maxSize, capacity)
+	return m.internalMap.SetEntries(new_entries)
+}
+
+func (m *Map[Key, Value]) indexOfText(key string) (int, error) {
+	if !m.HasEntries() {
+		return -1, nil
+	}
+
+	entries, err := m.internalMap.Entries()
+	if err != nil {
+		return -1, err
+	}
+	for i := 0; i < int(m.size); i++ {
+		entry_key, err := entries.At(i).Key()
+		if err != nil {
+			return -1, err
+		}
+		if entry_key.Text() == key {
+			return i, nil
+		}
+	}
+
+	return -1, nil
+}
+
+func copyEntry(dst protocol_types.Map_Entry, src protocol_types.Map_Entry) error {
+	key, err := src.Key()
+	if err != nil {
+		return err
+	}
+	value, err := src.Value()
+	if err != nil {
+		return err
+	}
+
+	err = dst.SetKey(key)
+	if err != nil {
+		return err
+	}
+	return dst.SetValue(value)
+}
+
+// Sort brings the used entries into canonical order by sorting them by their key bytes.
+// Text and Data keys are compared by their raw bytes, struct keys by their canonical form.
+// It fails with ErrDuplicateMapKey if two entries have the same key.
+func (m *Map[Key, Value]) Sort() error {
+	if !m.HasEntries() {
+		return nil
+	}
+
+	entries, err := m.internalMap.Entries()
+	if err != nil {
+		return err
+	}
+
+	type sortEntry struct {
+		key_bytes []byte
+		key       capnp.Ptr
+		value     capnp.Ptr
+	}
+	sorted := make([]sortEntry, m.size)
+	for i := range sorted {
+		entry := entries.At(i)
+		key, err := entry.Key()
+		if err != nil {
+			return err
+		}
+		value, err := entry.Value()
+		if err != nil {
+			return err
+		}
+		key_bytes, err := mapKeyBytes(key)
+		if err != nil {
+			return err
+		}
+		sorted[i] = sortEntry{key_bytes: key_bytes, key: key, value: value}
+	}
+
+	slices.SortStableFunc(sorted, func(a, b sortEntry) int {
+		return bytes.Compare(a.key_bytes, b.key_bytes)
+	})
+	for i := 1; i < len(sorted); i++ {
+		if bytes.Equal(sorted[i-1].key_bytes, sorted[i].key_bytes) {
+			return ErrDuplicateMapKey
+		}
+	}
+
+	for i, sorted_entry := range sorted {
+		entry := entries.At(i)
+		err = entry.SetKey(sorted_entry.key)
+		if err != nil {
+			return err
+		}
+		err = entry.SetValue(sorted_entry.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Canonicalize sorts and truncates the map and returns it in canonical form ready to be signed.
+func (m *Map[Key, Value]) Canonicalize() ([]byte, error) {
+	err := m.prepare()
+	if err != nil {
+		return nil, err
+	}
+	return capnp.Canonicalize(capnp.Struct(*m.internalMap))
+}
+
+// prepare sorts and truncates the map so it can be signed
+func (m *Map[Key, Value]) prepare() error {
+	err := m.Sort()
+	if err != nil {
+		return err
+	}
+	return m.Truncate()
+}
+
+// CanonicalizeMap returns the canonical form of a map with its entries sorted by key, see CanonicalSorted.
+// Contrary to Map.Canonicalize the given map is not modified, which makes it suitable for verifying received messages.
+func CanonicalizeMap(m protocol_types.Map) ([]byte, error) {
+	return CanonicalSorted(capnp.Struct(m), protocol_types.Map_TypeID)
+}
+
+func mapKeyBytes(key capnp.Ptr) ([]byte, error) {
+	if !key.IsValid() {
+		return nil, nil
+	}
+	if st := key.Struct(); st.IsValid() {
+		return capnp.Canonicalize(st)
+	}
+	return key.Data(), nil
 }
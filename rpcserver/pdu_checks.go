@@ -0,0 +1,228 @@
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+
+	capnp "capnproto.org/go/capnp/v3"
+
+	"github.com/MTRNord/matrix_protobuf_fed/pdu"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// PDUStatus is the outcome of checking the signatures of an inbound PDU
+type PDUStatus int
+
+const (
+	// Every server that had to sign the PDU did so with a valid signature
+	PDUAccepted PDUStatus = iota
+	// The keys of a server that had to sign the PDU could not be fetched, so its signature could not be checked yet.
+	// The PDU is handed on, but should not be trusted until the keys are available and the signature was checked.
+	// This is unrelated to soft failing events in the sense of the Matrix specification, which the handler decides.
+	PDUKeysUnavailable
+	// A signature is missing or invalid. The PDU is not handed on.
+	PDURejected
+)
+
+func (s PDUStatus) String() string {
+	switch s {
+	case PDUAccepted:
+		return "accepted"
+	case PDUKeysUnavailable:
+		return "keys unavailable"
+	case PDURejected:
+		return "rejected"
+	}
+	return "PDUStatus(" + strconv.Itoa(int(s)) + ")"
+}
+
+// PDUInfo is what the server determined about an inbound PDU before handing it on
+type PDUInfo struct {
+	EventID string
+	// The outcome of the signature check
+	Status PDUStatus
+	// Why the signatures could not be checked or the PDU was rejected
+	StatusErr error
+	// The content hash of the PDU was missing or did not match, so the content was tampered with.
+	// The PDU handed on is the redacted form of the received one.
+	Redacted bool
+}
+
+// SignPDU signs the redacted canonical form of the PDU with the signing key and adds the signature to the PDU.
+// The content hash has to be set before, as it is covered by the signature.
+func SignPDU(b *pdu.Builder, signingKey SigningKeyWrapper) error {
+	redacted, err := pdu.RedactedUnsigned(b)
+	if err != nil {
+		return err
+	}
+	signatures, err := b.Signatures()
+	if err != nil {
+		return err
+	}
+	builder, err := SignatureListBuilderFrom(signatures)
+	if err != nil {
+		return err
+	}
+	err = builder.Sign(signingKey.ServerName(), signingKey.KeyID(), signingKey.privateKey, capnp.Struct(redacted.Struct()), types.Transaction_PDU_TypeID)
+	if err != nil {
+		return err
+	}
+
+	list, err := builder.Build(b.Struct().Segment())
+	if err != nil {
+		return err
+	}
+	return b.SetSignatures(list)
+}
+
+// checkPDU computes the event ID of an inbound PDU, checks its signatures and its content hash as required by
+// https://spec.matrix.org/v1.9/server-server-api/#checks-performed-on-receipt-of-a-pdu
+// PDUs failing the hash check are redacted.
+//
+// A *PDUError is returned if the PDU is malformed or rejected.
+func (s RPCMatrixServer) checkPDU(ctx context.Context, event types.Transaction_PDU) (types.Transaction_PDU, PDUInfo, error) {
+	view, err := pdu.View(event)
+	if err != nil {
+		return event, PDUInfo{}, &PDUError{Errcode: ErrcodeBadJSON, Message: err.Error()}
+	}
+	event_id, err := pdu.EventID(view)
+	if err != nil {
+		return event, PDUInfo{}, &PDUError{Errcode: ErrcodeBadJSON, Message: err.Error()}
+	}
+	info := PDUInfo{EventID: event_id}
+
+	info.Status, info.StatusErr = s.verifyPDUSignatures(ctx, view)
+	switch info.Status {
+	case PDURejected:
+		log.Println("Rejecting event", event_id, ":", info.StatusErr)
+		return event, info, &PDUError{Errcode: ErrcodeForbidden, Message: info.StatusErr.Error()}
+	case PDUKeysUnavailable:
+		log.Println("Unable to check the signatures of event", event_id, ":", info.StatusErr)
+	}
+
+	err = pdu.VerifyContentHash(view)
+	if errors.Is(err, pdu.ErrContentHashMismatch) || errors.Is(err, pdu.ErrMissingContentHash) {
+		log.Println("Redacting event", event_id, ":", err)
+		redacted, err := pdu.Redact(view)
+		if err != nil {
+			return event, info, &PDUError{Errcode: ErrcodeBadJSON, Message: err.Error()}
+		}
+		info.Redacted = true
+		return redacted.Struct(), info, nil
+	}
+	if err != nil {
+		return event, info, &PDUError{Errcode: ErrcodeBadJSON, Message: err.Error()}
+	}
+	return event, info, nil
+}
+
+// verifyPDUSignatures checks that every server required by pdu.RequiredSigners signed the redacted canonical form of
+// the PDU. Keys we do not know are fetched with the KeyFetcher before the PDU is judged.
+func (s RPCMatrixServer) verifyPDUSignatures(ctx context.Context, event pdu.PDU) (PDUStatus, error) {
+	servers, err := pdu.RequiredSigners(event)
+	if err != nil {
+		return PDURejected, err
+	}
+	redacted, err := pdu.RedactedUnsigned(event)
+	if err != nil {
+		return PDURejected, err
+	}
+	message := capnp.Struct(redacted.Struct())
+	signatures, err := event.Signatures()
+	if err != nil {
+		return PDURejected, err
+	}
+
+	// Room versions 1 to 4 ignore how long keys are valid, later ones need keys valid when the event was sent.
+	// See https://spec.matrix.org/v1.9/rooms/v5/#signing-key-validity-period
+	ts := event.OriginServerTS()
+	switch event.RoomVersion() {
+	case "1", "2", "3", "4":
+		ts = 0
+	}
+	lookup := s.keyLookupAt(ts)
+
+	result, err := VerifyCapnproto(message, types.Transaction_PDU_TypeID, signatures, servers, lookup)
+	if err != nil {
+		return PDURejected, err
+	}
+	if result.Valid() {
+		return PDUAccepted, nil
+	}
+
+	unknown := unknownKeyServers(result)
+	if len(unknown) == 0 {
+		return PDURejected, result.Err()
+	}
+
+	fetch_errs := make(map[string]error)
+	for _, server := range unknown {
+		if server == s.keys.SigningKey().ServerName() {
+			continue
+		}
+		if s.key_fetcher == nil {
+			fetch_errs[server] = errors.New("no key fetcher")
+			continue
+		}
+		err := s.key_fetcher.FetchKeys(ctx, server)
+		if err != nil {
+			fetch_errs[server] = err
+		}
+	}
+	if len(fetch_errs) < len(unknown) {
+		result, err = VerifyCapnproto(message, types.Transaction_PDU_TypeID, signatures, servers, lookup)
+		if err != nil {
+			return PDURejected, err
+		}
+		if result.Valid() {
+			return PDUAccepted, nil
+		}
+	}
+
+	// Only servers whose keys could not be fetched are given the benefit of the doubt
+	unknown = unknownKeyServers(result)
+	var keys_err error
+	for _, server := range unknownOrInvalidServers(result) {
+		fetch_err, ok := fetch_errs[server]
+		if !ok || !slices.Contains(unknown, server) {
+			return PDURejected, result.Err()
+		}
+		keys_err = errors.Join(keys_err, fmt.Errorf("unable to fetch the keys of %s: %w", server, fetch_err))
+	}
+	return PDUKeysUnavailable, keys_err
+}
+
+// unknownKeyServers returns the servers without a valid signature whose signatures were all made with unknown keys
+func unknownKeyServers(result VerificationResult) []string {
+	var servers []string
+	for _, server := range unknownOrInvalidServers(result) {
+		if slices.Contains(result.MissingServers, server) {
+			continue
+		}
+		bad := slices.ContainsFunc(result.Signatures, func(r SignatureResult) bool {
+			return r.ServerName == server && r.Status != SignatureUnknownKey
+		})
+		if !bad {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+// unknownOrInvalidServers returns the expected servers without a valid signature
+func unknownOrInvalidServers(result VerificationResult) []string {
+	servers := slices.Clone(result.MissingServers)
+	for _, r := range result.Signatures {
+		valid := slices.ContainsFunc(result.Signatures, func(other SignatureResult) bool {
+			return other.ServerName == r.ServerName && other.Status == SignatureValid
+		})
+		if !valid && !slices.Contains(servers, r.ServerName) {
+			servers = append(servers, r.ServerName)
+		}
+	}
+	return servers
+}
@@ -0,0 +1,208 @@
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	protocol "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// ErrcodeUnknown is used for rejected PDUs if the handler did not return a PDUError
+const ErrcodeUnknown = "M_UNKNOWN"
+
+// ErrcodeBadJSON is used for PDUs whose event ID or content hash can not be determined
+const ErrcodeBadJSON = "M_BAD_JSON"
+
+// ErrcodeForbidden is used for PDUs without the required valid signatures
+const ErrcodeForbidden = "M_FORBIDDEN"
+
+// A PDUError rejects a single PDU of a transaction. The rest of the transaction is processed as usual.
+type PDUError struct {
+	// The Matrix error code, e.g. M_FORBIDDEN
+	Errcode string `json:"errcode"`
+	Message string `json:"error"`
+}
+
+func (e *PDUError) Error() string {
+	return e.Errcode + ": " + e.Message
+}
+
+// PDUResult is the outcome of processing one PDU of a transaction
+type PDUResult struct {
+	// The position of the PDU in the transaction, counting from 0
+	Index uint32 `json:"index"`
+	// May be empty if the receiving server could not determine the event ID
+	EventID string `json:"event_id,omitempty"`
+	// nil if the PDU was accepted
+	Error *PDUError `json:"error,omitempty"`
+}
+
+// Accepted returns true if the PDU was processed without errors
+func (r PDUResult) Accepted() bool {
+	return r.Error == nil
+}
+
+// newPDUResult turns the error returned by the TransactionHandler into the result of the PDU.
+// Errors that are not a PDUError are reported with ErrcodeUnknown.
+func newPDUResult(index uint32, eventID string, err error) PDUResult {
+	result := PDUResult{Index: index, EventID: eventID}
+	if err == nil {
+		return result
+	}
+
+	var pdu_err *PDUError
+	if errors.As(err, &pdu_err) {
+		result.Error = pdu_err
+	} else {
+		result.Error = &PDUError{Errcode: ErrcodeUnknown, Message: err.Error()}
+	}
+	return result
+}
+
+// WriteTo fills the capnproto representation of the result
+func (r PDUResult) WriteTo(result types.PDUResult) error {
+	result.SetIndex(r.Index)
+	err := result.SetEventID(r.EventID)
+	if err != nil {
+		return err
+	}
+	if r.Error == nil {
+		result.SetAccepted()
+		return nil
+	}
+
+	rejected, err := result.NewRejected()
+	if err != nil {
+		return err
+	}
+	err = rejected.SetErrcode(r.Error.Errcode)
+	if err != nil {
+		return err
+	}
+	return rejected.SetErrorMessage(r.Error.Message)
+}
+
+// ReadPDUResult copies a result out of its capnproto representation
+func ReadPDUResult(result types.PDUResult) (PDUResult, error) {
+	event_id, err := result.EventID()
+	if err != nil {
+		return PDUResult{}, err
+	}
+	read := PDUResult{Index: result.Index(), EventID: event_id}
+
+	switch result.Which() {
+	case types.PDUResult_Which_accepted:
+		return read, nil
+	case types.PDUResult_Which_rejected:
+		rejected, err := result.Rejected()
+		if err != nil {
+			return PDUResult{}, err
+		}
+		errcode, err := rejected.Errcode()
+		if err != nil {
+			return PDUResult{}, err
+		}
+		message, err := rejected.ErrorMessage()
+		if err != nil {
+			return PDUResult{}, err
+		}
+		read.Error = &PDUError{Errcode: errcode, Message: message}
+		return read, nil
+	default:
+		return PDUResult{}, fmt.Errorf("unknown PDU result %d", result.Which())
+	}
+}
+
+// writePDUResult streams the result of a PDU to the sending server
+func writePDUResult(ctx context.Context, client protocol.StreamCallback, result PDUResult) error {
+	return client.Write(ctx, func(p protocol.StreamCallback_write_Params) error {
+		value, err := types.NewPDUResult(p.Segment())
+		if err != nil {
+			return err
+		}
+		err = result.WriteTo(value)
+		if err != nil {
+			return err
+		}
+		return p.SetValue(value.ToPtr())
+	})
+}
+
+// TransactionResults collects the PDU results of a sendTransactions call on the sending side.
+// Pass it as results using protocol.StreamCallback_ServerToClient.
+type TransactionResults struct {
+	mu      sync.Mutex
+	results []PDUResult
+	done    chan struct{}
+	// Called for every result as it arrives. May be nil.
+	onResult func(PDUResult)
+}
+
+// NewTransactionResults creates a TransactionResults. onResult is called for every result as it arrives and may be nil.
+func NewTransactionResults(onResult func(PDUResult)) *TransactionResults {
+	return &TransactionResults{
+		done:     make(chan struct{}),
+		onResult: onResult,
+	}
+}
+
+func (t *TransactionResults) Write(ctx context.Context, call protocol.StreamCallback_write) error {
+	value, err := call.Args().Value()
+	if err != nil {
+		return err
+	}
+	result, err := ReadPDUResult(types.PDUResult(value.Struct()))
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.results = append(t.results, result)
+	t.mu.Unlock()
+	if t.onResult != nil {
+		t.onResult(result)
+	}
+	return nil
+}
+
+func (t *TransactionResults) Done(ctx context.Context, call protocol.StreamCallback_done) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	select {
+	case <-t.done:
+	default:
+		close(t.done)
+	}
+	return nil
+}
+
+// Wait blocks until the receiving server finished the transaction and returns the results of all PDUs.
+func (t *TransactionResults) Wait(ctx context.Context) ([]PDUResult, error) {
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.results), nil
+}
+
+// Rejected returns the results of the rejected PDUs received so far
+func (t *TransactionResults) Rejected() []PDUResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var rejected []PDUResult
+	for _, result := range t.results {
+		if !result.Accepted() {
+			rejected = append(rejected, result)
+		}
+	}
+	return rejected
+}
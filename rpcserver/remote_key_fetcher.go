@@ -0,0 +1,129 @@
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"capnproto.org/go/capnp/v3/rpc"
+
+	protocol "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1"
+)
+
+// MinRefetchInterval is how long the outcome of fetching the keys of a server is reused before asking it again.
+// This keeps a server that signs with unknown keys from making us connect to it for every event.
+const MinRefetchInterval = time.Minute
+
+// KeyFetchTimeout is how long fetching the keys of a server may take
+const KeyFetchTimeout = time.Second * 30
+
+// A Dialer opens a connection to the federation endpoint of another server.
+// The connection is closed once it is no longer needed.
+type Dialer interface {
+	Dial(ctx context.Context, destination string) (*rpc.Conn, error)
+}
+
+// A KeyStorer keeps the keys fetched by a RemoteKeyFetcher, e.g. a keystore.KeyStore.
+type KeyStorer interface {
+	Store(keys *ServerKeys) error
+}
+
+// RemoteKeyFetcher fetches the keys of other servers via getKeys and hands them to a KeyStorer.
+// It implements KeyFetcher.
+type RemoteKeyFetcher struct {
+	store  KeyStorer
+	dialer Dialer
+
+	mu sync.Mutex
+	// Running fetches and the finished ones within MinRefetchInterval
+	attempts map[string]*fetchAttempt
+}
+
+// fetchAttempt is a running or finished fetch of the keys of a server
+type fetchAttempt struct {
+	// Closed once the fetch finished
+	done chan struct{}
+	err  error
+}
+
+// NewRemoteKeyFetcher creates a RemoteKeyFetcher connecting to other servers with the dialer
+func NewRemoteKeyFetcher(store KeyStorer, dialer Dialer) *RemoteKeyFetcher {
+	return &RemoteKeyFetcher{
+		store:    store,
+		dialer:   dialer,
+		attempts: make(map[string]*fetchAttempt),
+	}
+}
+
+// FetchKeys fetches the keys of the server and stores them if their self-signatures are valid.
+// Concurrent calls for the same server share one fetch, and within MinRefetchInterval the previous outcome is
+// returned without connecting to the server again.
+//
+// The fetch is not bound to ctx, so a caller giving up does not abort it for the others. It is limited by
+// KeyFetchTimeout instead.
+func (f *RemoteKeyFetcher) FetchKeys(ctx context.Context, serverName string) error {
+	f.mu.Lock()
+	attempt, ok := f.attempts[serverName]
+	if !ok {
+		attempt = &fetchAttempt{done: make(chan struct{})}
+		f.attempts[serverName] = attempt
+		go f.run(serverName, attempt)
+	}
+	f.mu.Unlock()
+
+	select {
+	case <-attempt.done:
+		return attempt.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run fetches the keys and forgets the attempt once its outcome may no longer be reused
+func (f *RemoteKeyFetcher) run(serverName string, attempt *fetchAttempt) {
+	ctx, cancel := context.WithTimeout(context.Background(), KeyFetchTimeout)
+	attempt.err = f.fetch(ctx, serverName)
+	cancel()
+	close(attempt.done)
+
+	// A fetch that ran out of time says nothing about the server, the next call tries again
+	if errors.Is(attempt.err, context.Canceled) || errors.Is(attempt.err, context.DeadlineExceeded) {
+		f.forget(serverName, attempt)
+		return
+	}
+	time.AfterFunc(MinRefetchInterval, func() {
+		f.forget(serverName, attempt)
+	})
+}
+
+func (f *RemoteKeyFetcher) forget(serverName string, attempt *fetchAttempt) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.attempts[serverName] == attempt {
+		delete(f.attempts, serverName)
+	}
+}
+
+func (f *RemoteKeyFetcher) fetch(ctx context.Context, serverName string) error {
+	conn, err := f.dialer.Dial(ctx, serverName)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	client := protocol.MatrixFederation(conn.Bootstrap(ctx))
+	defer client.Release()
+
+	servers, err := FetchServerKeys(ctx, client)
+	if err != nil {
+		return err
+	}
+	for _, keys := range servers {
+		// A server only speaks for itself when asked directly
+		if keys.ServerName == serverName {
+			return f.store.Store(keys)
+		}
+	}
+	return fmt.Errorf("%s sent no valid keys of its own", serverName)
+}
@@ -0,0 +1,205 @@
+package rpcserver
+
+import (
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrReplayedRequest is returned if the nonce of a signed request was already seen from the same origin.
+var ErrReplayedRequest = errors.New("request was replayed")
+
+// ErrReplayCacheFull is returned if too many requests were made within the replay window to remember their nonces.
+var ErrReplayCacheFull = errors.New("too many recent requests to check for replays")
+
+const (
+	// DefaultReplayWindow is how far the originServerTS of a request may be away from our time by default
+	DefaultReplayWindow = time.Minute * 5
+	// DefaultReplayCacheSize is how many nonces are remembered by default
+	DefaultReplayCacheSize = 100000
+)
+
+type seenNonce struct {
+	Origin string `json:"origin"`
+	Nonce  []byte `json:"nonce"`
+	// POSIX timestamp in milliseconds after which the request is outside the window anyway
+	ExpiresTS int64 `json:"expires_ts"`
+}
+
+// nonceCache remembers the nonces of recent requests.
+//
+// A nonce only has to be remembered as long as its request would be accepted by the timestamp check.
+// If the cache is full new requests are rejected until nonces expire, as forgetting a nonce earlier would allow its
+// request to be replayed.
+type nonceCache struct {
+	mu         sync.Mutex
+	window     time.Duration
+	maxEntries int
+	path       string
+	seen       map[string]struct{}
+	// Nonces ordered by when they expire. The timestamps of requests are not in the order they arrive in.
+	expiry nonceHeap
+}
+
+// newNonceCache creates a nonceCache. If path is not empty previously saved nonces are loaded from it.
+func newNonceCache(window time.Duration, maxEntries int, path string) (*nonceCache, error) {
+	if window <= 0 || maxEntries <= 0 {
+		return nil, errors.New("replay window and cache size have to be positive")
+	}
+
+	cache := &nonceCache{
+		window:     window,
+		maxEntries: maxEntries,
+		path:       path,
+		seen:       make(map[string]struct{}),
+	}
+	if path == "" {
+		return cache, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved []seenNonce
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	now := time.Now().UnixMilli()
+	for _, nonce := range saved {
+		if nonce.ExpiresTS > now {
+			cache.add(nonce)
+		}
+	}
+	return cache, nil
+}
+
+// checkTimestamp rejects timestamps that are further away from our time than the window
+func (c *nonceCache) checkTimestamp(ts int64) error {
+	now := time.Now().UnixMilli()
+	window := c.window.Milliseconds()
+	if ts < now-window || ts > now+window {
+		return fmt.Errorf("originServerTS %d is outside the accepted window of %s", ts, c.window)
+	}
+	return nil
+}
+
+// checkNonce rejects nonces that were already seen from the origin and remembers new ones.
+// The timestamp has to be checked with checkTimestamp before.
+func (c *nonceCache) checkNonce(origin string, nonce []byte, ts int64) error {
+	if len(nonce) == 0 {
+		return errors.New("missing nonce")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire()
+	if _, ok := c.seen[nonceKey(origin, nonce)]; ok {
+		return ErrReplayedRequest
+	}
+	if len(c.expiry) >= c.maxEntries {
+		return ErrReplayCacheFull
+	}
+
+	c.add(seenNonce{
+		Origin:    origin,
+		Nonce:     nonce,
+		ExpiresTS: ts + c.window.Milliseconds(),
+	})
+	return nil
+}
+
+// save writes the remembered nonces to the file the cache was created with
+func (c *nonceCache) save() error {
+	if c.path == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.expire()
+	data, err := json.Marshal(c.expiry)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// add remembers a nonce. The caller has to hold the lock.
+func (c *nonceCache) add(nonce seenNonce) {
+	c.seen[nonceKey(nonce.Origin, nonce.Nonce)] = struct{}{}
+	heap.Push(&c.expiry, nonce)
+}
+
+// expire forgets nonces whose requests are outside the window. The caller has to hold the lock.
+func (c *nonceCache) expire() {
+	now := time.Now().UnixMilli()
+	for len(c.expiry) > 0 && c.expiry[0].ExpiresTS <= now {
+		nonce := heap.Pop(&c.expiry).(seenNonce)
+		delete(c.seen, nonceKey(nonce.Origin, nonce.Nonce))
+	}
+}
+
+// nonceHeap is a heap of nonces with the one that expires first at the front
+type nonceHeap []seenNonce
+
+func (h nonceHeap) Len() int           { return len(h) }
+func (h nonceHeap) Less(i, j int) bool { return h[i].ExpiresTS < h[j].ExpiresTS }
+func (h nonceHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *nonceHeap) Push(x any) {
+	*h = append(*h, x.(seenNonce))
+}
+
+func (h *nonceHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	old[len(old)-1] = seenNonce{}
+	*h = old[:len(old)-1]
+	return last
+}
+
+// SetReplayProtection configures how long signed requests are accepted after they were made and how many nonces
+// are remembered to reject replays. If path is not empty the nonces are loaded from it and saved to it on Drain.
+// While maxEntries nonces are remembered, further requests are rejected with ErrReplayCacheFull.
+//
+// By default the window is DefaultReplayWindow and DefaultReplayCacheSize nonces are kept in memory.
+func (s *RPCMatrixServer) SetReplayProtection(window time.Duration, maxEntries int, path string) error {
+	cache, err := newNonceCache(window, maxEntries, path)
+	if err != nil {
+		return err
+	}
+	s.nonces = cache
+	return nil
+}
+
+func nonceKey(origin string, nonce []byte) string {
+	return origin + "\x00" + string(nonce)
+}
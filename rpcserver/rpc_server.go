@@ -1,52 +1,74 @@
 package rpcserver
 
 import (
-	"bytes"
 	"context"
-	"crypto/ed25519"
-	"encoding/base64"
-	"log"
-	"time"
-
-	capnp "capnproto.org/go/capnp/v3"
+	"errors"
 
 	protocol "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1"
-	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
 )
 
-type SigningKeyWrapper struct {
-	*protocol.MatrixFederation
-	entityName string
-	keyID      KeyID
-	privateKey ed25519.PrivateKey
-}
-
 type RPCMatrixServer struct {
-	signing_key SigningKeyWrapper
+	// Our own signing, verify and old verify keys
+	keys *KeyRing
+	// Keys of other servers used to answer notary requests. May be nil.
+	key_provider KeyProvider
+	// Keys of other servers used to check their signatures. May be nil.
+	verify_key_source VerifyKeySource
+	// Fetches unknown keys of other servers when checking the signatures of requests and PDUs. May be nil.
+	key_fetcher KeyFetcher
+	// Source of the events served via Backfill. May be nil.
+	event_store EventStore
+	// Processes inbound transactions. May be nil.
+	transaction_handler TransactionHandler
+	// Recently completed inbound transactions to answer retries
+	transactions *transactionLog
+	// Nonces of recent signed requests to reject replays
+	nonces  *nonceCache
+	streams *streamTracker
 }
 
-func NewServer() RPCMatrixServer {
-	// Check JSON verification using the test vectors from https://matrix.org/docs/spec/appendices.html
-	seed, err := base64.RawStdEncoding.DecodeString("YJDBA9Xnr2sVqXD9Vj7XVUnmFZcZrlw8Md7kMW+3XA1")
+// NewServer creates a new server using the given signing keys.
+// The first key is used for signing, all of them are published as verify keys.
+func NewServer(signingKeys []SigningKeyWrapper) (RPCMatrixServer, error) {
+	keys, err := NewKeyRing(signingKeys)
 	if err != nil {
-		log.Fatal(err)
+		return RPCMatrixServer{}, err
 	}
-	random := bytes.NewBuffer(seed)
-	entityName := "domain"
-	keyID := KeyID("ed25519:1")
-
-	_, privateKey, err := ed25519.GenerateKey(random)
+	nonces, err := newNonceCache(DefaultReplayWindow, DefaultReplayCacheSize, "")
 	if err != nil {
-		log.Fatal(err)
+		return RPCMatrixServer{}, err
+	}
+	transactions, err := newTransactionLog(DefaultTransactionLogSize, "")
+	if err != nil {
+		return RPCMatrixServer{}, err
 	}
 
 	return RPCMatrixServer{
-		signing_key: SigningKeyWrapper{
-			entityName: entityName,
-			keyID:      keyID,
-			privateKey: privateKey,
-		},
-	}
+		keys:         keys,
+		transactions: transactions,
+		nonces:       nonces,
+		streams:      &streamTracker{},
+	}, nil
+}
+
+// KeyRing returns our own keys. Changes to it are picked up by the running server.
+func (s RPCMatrixServer) KeyRing() *KeyRing {
+	return s.keys
+}
+
+// SetKeyProvider sets where the keys of other servers are looked up when acting as a notary
+func (s *RPCMatrixServer) SetKeyProvider(provider KeyProvider) {
+	s.key_provider = provider
+}
+
+// Drain refuses new GetKeys, SendTransactions and Backfill streams and waits until the in-flight ones are finished.
+// Afterwards the nonces of recent requests are saved if replay protection is persisted and the transaction log
+// is closed. This also happens if ctx ends before the streams are finished, the errors are joined.
+func (s RPCMatrixServer) Drain(ctx context.Context) error {
+	drain_err := s.streams.drain(ctx)
+	save_err := s.nonces.save()
+	close_err := s.transactions.close()
+	return errors.Join(drain_err, save_err, close_err)
 }
 
 func (s RPCMatrixServer) GetVersion(ctx context.Context, call protocol.MatrixFederation_getVersion) error {
@@ -66,104 +88,67 @@ func (s RPCMatrixServer) GetVersion(ctx context.Context, call protocol.MatrixFed
 	return nil
 }
 
+// GetKeys answers key queries of other servers.
+//
+// Without server_keys this behaves like the direct key query and only our own keys are streamed.
+// With server_keys we act as a notary: For every requested server we know suitable keys for, one batch of
+// ServerKeysResponse chunks is streamed. Each chunk carries the signatures of the original server and our own.
 func (s RPCMatrixServer) GetKeys(ctx context.Context, call protocol.MatrixFederation_getKeys) error {
+	if !s.streams.begin() {
+		return ErrDraining
+	}
+	defer s.streams.end()
 	call.Go()
 
-	client := call.Args().Callback()
+	args := call.Args()
+	client := args.Callback()
 	defer client.Release()
 
-	err := client.Write(ctx, func(p protocol.StreamCallback_write_Params) error {
-		log.Println("Sending server keys metadata response...")
-
-		response, err := types.NewServerKeysResponse(p.Segment())
+	servers := []*ServerKeys{s.ownKeys()}
+	if args.HasServer_keys() {
+		query, err := args.Server_keys()
 		if err != nil {
 			return err
 		}
-		p.SetValue(response.ToPtr())
-
-		metadata, err := response.NewMetadata()
+		servers, err = s.queryKeys(query)
 		if err != nil {
 			return err
 		}
-		metadata.SetServerName("placeholder")
-		metadata.SetValidUntilTS(time.Now().UTC().Add(time.Hour * 24).Unix())
-
-		metadata_bytes, err := capnp.Canonicalize(capnp.Struct(metadata))
-		if err != nil {
-			return err
-		}
-		signatures, err := response.NewSignatures(1)
-		if err != nil {
-			return err
-		}
-		err = SignCapnproto("placeholder", "placeholder", s.signing_key.privateKey, metadata_bytes, &signatures)
-		if err != nil {
-			return err
-		}
-		return response.SetSignatures(signatures)
-	})
-	if err != nil {
-		return err
 	}
 
-	err = client.Write(ctx, func(p protocol.StreamCallback_write_Params) error {
-		log.Println("Sending server keys verify_keys response...")
-
-		response, err := types.NewServerKeysResponse(p.Segment())
-		if err != nil {
-			return err
-		}
-		p.SetValue(response.ToPtr())
-
-		verify_keys_raw, err := response.NewVerifyKeys()
+	for _, keys := range servers {
+		err := s.writeServerKeys(ctx, client, keys)
 		if err != nil {
 			return err
 		}
-		verify_keys := FromMap(&verify_keys_raw, 1)
-		key, err := capnp.NewText(verify_keys.Segment(), "placeholder")
-		if err != nil {
-			return err
-		}
-
-		log.Println("Original Key bytes:", s.signing_key.privateKey.Public().(ed25519.PublicKey))
-		data, err := capnp.NewData(verify_keys.Segment(), s.signing_key.privateKey.Public().(ed25519.PublicKey))
-		if err != nil {
-			return err
-		}
-		verify_keys.AddEntry(key.ToPtr(), data.ToPtr())
-
-		verify_keys_bytes, err := capnp.Canonicalize(capnp.Struct(verify_keys_raw))
-		if err != nil {
-			return err
-		}
-		signatures, err := response.NewSignatures(1)
-		if err != nil {
-			return err
-		}
-		err = SignCapnproto("placeholder", "placeholder", s.signing_key.privateKey, verify_keys_bytes, &signatures)
-		if err != nil {
-			return err
-		}
-		return response.SetSignatures(signatures)
-	})
-	if err != nil {
-		return err
 	}
 
 	_, release := client.Done(ctx, nil)
 	defer release()
 
-	if err := client.WaitStreaming(); err != nil {
-		return err
-	}
-
-	return err
+	return client.WaitStreaming()
 }
 
-func (s RPCMatrixServer) SendTransactions(context.Context, protocol.MatrixFederation_sendTransactions) error {
-	return nil
-}
+// SendTransactions hands out a stream the calling server pushes one transaction into.
+// See transactionStream for the order of the chunks. The PDU results are written to the results stream of the caller
+// if it passed one. The stream counts as in-flight until done is called or the caller releases it.
+func (s RPCMatrixServer) SendTransactions(ctx context.Context, call protocol.MatrixFederation_sendTransactions) error {
+	if !s.streams.begin() {
+		return ErrDraining
+	}
 
-func (s RPCMatrixServer) Backfill(context.Context, protocol.MatrixFederation_backfill) error {
-	return nil
+	res, err := call.AllocResults()
+	if err != nil {
+		s.streams.end()
+		return err
+	}
+
+	results_ctx, cancel_results := context.WithCancel(context.Background())
+	callback := protocol.StreamCallback_ServerToClient(&transactionStream{
+		server:         s,
+		results:        call.Args().Results().AddRef(),
+		results_ctx:    results_ctx,
+		cancel_results: cancel_results,
+	})
+	return res.SetCallback(callback)
 }
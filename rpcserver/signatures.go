@@ -0,0 +1,164 @@
+package rpcserver
+
+import (
+	"crypto/ed25519"
+	"slices"
+
+	capnp "capnproto.org/go/capnp/v3"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// SignatureListBuilder collects signatures of multiple servers and key IDs and writes them into a types.Signature_List.
+//
+// Capnproto lists have a fixed size, so adding a signature to an existing list means building a new one.
+// The builder always writes servers and key IDs sorted by name so the result is deterministic for canonicalization.
+type SignatureListBuilder struct {
+	signatures map[string]map[KeyID][]byte
+}
+
+// NewSignatureListBuilder creates an empty SignatureListBuilder
+func NewSignatureListBuilder() *SignatureListBuilder {
+	return &SignatureListBuilder{
+		signatures: make(map[string]map[KeyID][]byte),
+	}
+}
+
+// SignatureListBuilderFrom creates a SignatureListBuilder containing all signatures of an existing list.
+// Entries without a server name are skipped as they are unused slots.
+func SignatureListBuilderFrom(list types.Signature_List) (*SignatureListBuilder, error) {
+	builder := NewSignatureListBuilder()
+	for i := 0; i < list.Len(); i++ {
+		signature := list.At(i)
+		server, err := signature.Server()
+		if err != nil {
+			return nil, err
+		}
+		if server == "" || !signature.HasSignatures() {
+			continue
+		}
+
+		signatures_map, err := signature.Signatures()
+		if err != nil {
+			return nil, err
+		}
+		entries, err := signatures_map.Entries()
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < entries.Len(); j++ {
+			entry := entries.At(j)
+			key_id_ptr, err := entry.Key()
+			if err != nil {
+				return nil, err
+			}
+			signature_ptr, err := entry.Value()
+			if err != nil {
+				return nil, err
+			}
+			builder.Add(server, KeyID(key_id_ptr.Text()), signature_ptr.Data())
+		}
+	}
+	return builder, nil
+}
+
+// Add adds a signature. An existing signature of the same server and key ID gets replaced.
+func (b *SignatureListBuilder) Add(server string, keyID KeyID, signature []byte) {
+	server_signatures, ok := b.signatures[server]
+	if !ok {
+		server_signatures = make(map[KeyID][]byte)
+		b.signatures[server] = server_signatures
+	}
+	server_signatures[keyID] = slices.Clone(signature)
+}
+
+// AddAll adds all given signatures
+func (b *SignatureListBuilder) AddAll(signatures Signatures) {
+	for server, server_signatures := range signatures {
+		for key_id, signature := range server_signatures {
+			b.Add(server, key_id, signature)
+		}
+	}
+}
+
+// Signatures returns a copy of the collected signatures
+func (b *SignatureListBuilder) Signatures() Signatures {
+	result := make(Signatures, len(b.signatures))
+	for server, server_signatures := range b.signatures {
+		result[server] = make(map[KeyID][]byte, len(server_signatures))
+		for key_id, signature := range server_signatures {
+			result[server][key_id] = slices.Clone(signature)
+		}
+	}
+	return result
+}
+
+// Sign signs the canonical form of message, a struct of the given type, with the private key and adds the signature.
+// Maps in the message are signed with their entries sorted, see CanonicalSorted.
+func (b *SignatureListBuilder) Sign(server string, keyID KeyID, privateKey ed25519.PrivateKey, message capnp.Struct, typeID uint64) error {
+	canonical, err := CanonicalSorted(message, typeID)
+	if err != nil {
+		return err
+	}
+	b.Add(server, keyID, ed25519.Sign(privateKey, canonical))
+	return nil
+}
+
+// Len returns the number of signing servers
+func (b *SignatureListBuilder) Len() int {
+	return len(b.signatures)
+}
+
+// Build writes the collected signatures as a new list into the segment
+func (b *SignatureListBuilder) Build(s *capnp.Segment) (types.Signature_List, error) {
+	servers := make([]string, 0, len(b.signatures))
+	for server := range b.signatures {
+		servers = append(servers, server)
+	}
+	slices.Sort(servers)
+
+	list, err := types.NewSignature_List(s, int32(len(servers)))
+	if err != nil {
+		return list, err
+	}
+
+	for i, server := range servers {
+		signature := list.At(i)
+		err = signature.SetServer(server)
+		if err != nil {
+			return list, err
+		}
+
+		server_signatures := b.signatures[server]
+		key_ids := make([]KeyID, 0, len(server_signatures))
+		for key_id := range server_signatures {
+			key_ids = append(key_ids, key_id)
+		}
+		slices.Sort(key_ids)
+
+		signatures_map, err := signature.NewSignatures()
+		if err != nil {
+			return list, err
+		}
+		signatures_map_wrapper, err := FromMap(&signatures_map, int32(len(key_ids)))
+		if err != nil {
+			return list, err
+		}
+		for _, key_id := range key_ids {
+			key, err := capnp.NewText(s, string(key_id))
+			if err != nil {
+				return list, err
+			}
+
+			data, err := capnp.NewData(s, server_signatures[key_id])
+			if err != nil {
+				return list, err
+			}
+			err = signatures_map_wrapper.AddEntry(key.ToPtr(), data.ToPtr())
+			if err != nil {
+				return list, err
+			}
+		}
+	}
+
+	return list, nil
+}
@@ -0,0 +1,135 @@
+package rpcserver
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	protocol "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1"
+)
+
+// ErrNoSigningKeys is returned when a key file does not contain any signing key.
+var ErrNoSigningKeys = errors.New("no signing keys found")
+
+// The version part of a key ID. See https://spec.matrix.org/v1.9/server-server-api/#publishing-keys
+var keyVersionRegex = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// SigningKeyWrapper holds a private signing key together with the identity it signs for.
+type SigningKeyWrapper struct {
+	*protocol.MatrixFederation
+	entityName string
+	keyID      KeyID
+	privateKey ed25519.PrivateKey
+}
+
+// NewSigningKey creates a SigningKeyWrapper for the given server name, key ID and private key
+func NewSigningKey(serverName string, keyID KeyID, privateKey ed25519.PrivateKey) SigningKeyWrapper {
+	return SigningKeyWrapper{
+		entityName: serverName,
+		keyID:      keyID,
+		privateKey: privateKey,
+	}
+}
+
+// ServerName returns the name of the server the key signs for
+func (k SigningKeyWrapper) ServerName() string {
+	return k.entityName
+}
+
+// KeyID returns the ID of the key, e.g. "ed25519:a_abcd"
+func (k SigningKeyWrapper) KeyID() KeyID {
+	return k.keyID
+}
+
+// PublicKey returns the public part of the key
+func (k SigningKeyWrapper) PublicKey() ed25519.PublicKey {
+	return k.privateKey.Public().(ed25519.PublicKey)
+}
+
+// LoadSigningKeys reads the signing keys from a Synapse-format key file.
+// See ReadSigningKeys for the format.
+func LoadSigningKeys(serverName string, path string) ([]SigningKeyWrapper, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	keys, err := ReadSigningKeys(serverName, file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// ReadSigningKeys reads signing keys in the format used by Synapse.
+//
+// Each line contains one key as "<algorithm> <version> <base64 seed>", e.g.
+//
+//	ed25519 a_abcd YJDBA9Xnr2sVqXD9Vj7XVUnmFZcZrlw8Md7kMW+3XA1
+//
+// Empty lines are ignored. At least one key is required.
+func ReadSigningKeys(serverName string, r io.Reader) ([]SigningKeyWrapper, error) {
+	var keys []SigningKeyWrapper
+
+	scanner := bufio.NewScanner(r)
+	line_number := 0
+	for scanner.Scan() {
+		line_number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		key, err := parseSigningKey(serverName, line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line_number, err)
+		}
+		for _, existing := range keys {
+			if existing.keyID == key.keyID {
+				return nil, fmt.Errorf("line %d: duplicate key ID %q", line_number, key.keyID)
+			}
+		}
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrNoSigningKeys
+	}
+	return keys, nil
+}
+
+func parseSigningKey(serverName string, line string) (SigningKeyWrapper, error) {
+	parts := strings.Fields(line)
+	if len(parts) != 3 {
+		return SigningKeyWrapper{}, fmt.Errorf("expected 3 fields but got %d", len(parts))
+	}
+
+	algorithm, version, seed_base64 := parts[0], parts[1], parts[2]
+	if algorithm != "ed25519" {
+		return SigningKeyWrapper{}, fmt.Errorf("unsupported key algorithm %q", algorithm)
+	}
+	if !keyVersionRegex.MatchString(version) {
+		return SigningKeyWrapper{}, fmt.Errorf("invalid key version %q", version)
+	}
+
+	// Synapse writes unpadded base64 but accepts padded input as well
+	seed, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(seed_base64, "="))
+	if err != nil {
+		return SigningKeyWrapper{}, fmt.Errorf("invalid key seed: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return SigningKeyWrapper{}, fmt.Errorf("invalid key seed length %d, expected %d", len(seed), ed25519.SeedSize)
+	}
+
+	return NewSigningKey(serverName, KeyID(algorithm+":"+version), ed25519.NewKeyFromSeed(seed)), nil
+}
@@ -0,0 +1,53 @@
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrDraining is returned to callers that try to open a new stream while the server is shutting down.
+var ErrDraining = errors.New("rpcserver: server is shutting down")
+
+// streamTracker keeps track of the in-flight streams so they can be drained on shutdown.
+type streamTracker struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	draining bool
+}
+
+// begin registers a new stream. It returns false if the tracker is draining and the stream must not be started.
+func (t *streamTracker) begin() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return false
+	}
+	t.wg.Add(1)
+	return true
+}
+
+// end marks a stream started with begin as finished.
+func (t *streamTracker) end() {
+	t.wg.Done()
+}
+
+// drain refuses new streams and waits for the in-flight ones to finish or for ctx to expire.
+func (t *streamTracker) drain(ctx context.Context) error {
+	t.mu.Lock()
+	t.draining = true
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
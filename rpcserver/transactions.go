@@ -0,0 +1,266 @@
+package rpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	protocol "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+)
+
+// ErrInvalidTransactionStream is wrapped by errors returned when the chunks of a transaction stream are out of order.
+var ErrInvalidTransactionStream = errors.New("invalid transaction stream")
+
+// TransactionInfo is the metadata of an inbound transaction
+type TransactionInfo struct {
+	TxnID string
+	// The authenticated server the transaction was sent by
+	Origin string
+	// POSIX timestamp in milliseconds on the origin server when the transaction started
+	OriginServerTS int64
+}
+
+// A TransactionHandler processes the PDUs and EDUs of inbound transactions.
+//
+// The PDUs and EDUs are only valid during the call, anything that is kept has to be copied.
+type TransactionHandler interface {
+	// HandlePDU processes a single PDU. An error only rejects this PDU and is reported to the sending server in the
+	// PDU results. Return a *PDUError to choose the error code, other errors are reported as ErrcodeUnknown.
+	// info tells what the server already checked: PDUs with invalid signatures are not passed, PDUs whose keys could
+	// not be fetched are passed with PDUKeysUnavailable and PDUs failing the content hash check are passed redacted.
+	HandlePDU(ctx context.Context, txn TransactionInfo, pdu types.Transaction_PDU, info PDUInfo) error
+	// HandleEDU processes a single EDU. An error aborts the stream and is returned to the sending server.
+	HandleEDU(ctx context.Context, txn TransactionInfo, edu types.Transaction_EDU) error
+	// TransactionDone is called after the last PDU or EDU of the transaction was handled and the transaction was
+	// recorded as completed. If it returns an error, the record is dropped so the sending server can retry.
+	TransactionDone(ctx context.Context, txn TransactionInfo) error
+}
+
+// SetTransactionHandler sets who processes inbound transactions. Without a handler PDUs and EDUs are dropped.
+func (s *RPCMatrixServer) SetTransactionHandler(handler TransactionHandler) {
+	s.transaction_handler = handler
+}
+
+// transactionStream is the StreamCallback handed out by SendTransactions.
+//
+// The first chunk has to be the metadata, carrying the AuthData of the sending server. It is followed by any number
+// of PDU and EDU chunks which are passed to the handler. The result of every PDU is written to results.
+// The stream ends with done.
+//
+// If the transaction was completed before, the previous results are written to results right after the metadata and
+// the following PDUs and EDUs are ignored.
+type transactionStream struct {
+	server RPCMatrixServer
+	// Where the PDU results are sent to. May be invalid if the sending server is not interested in them.
+	results protocol.StreamCallback
+	// Context for writing to results. The context of a write call ends with the call, but results are streamed
+	// asynchronously and may still be in flight afterwards.
+	results_ctx    context.Context
+	cancel_results context.CancelFunc
+	// Set once the metadata chunk was accepted
+	txn *TransactionInfo
+	// The results of the PDUs received so far
+	pdu_results []PDUResult
+	// Set if the transaction was completed before
+	duplicate bool
+	mu        sync.Mutex
+	finished  bool
+}
+
+func (t *transactionStream) Write(ctx context.Context, call protocol.StreamCallback_write) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.finished {
+		return fmt.Errorf("%w: stream is already done", ErrInvalidTransactionStream)
+	}
+
+	value, err := call.Args().Value()
+	if err != nil {
+		return err
+	}
+	transaction := types.Transaction(value.Struct())
+
+	switch transaction.Which() {
+	case types.Transaction_Which_metadata:
+		if t.txn != nil {
+			return fmt.Errorf("%w: only one transaction per stream is allowed, got a second metadata chunk", ErrInvalidTransactionStream)
+		}
+		return t.handleMetadata(ctx, transaction)
+	case types.Transaction_Which_pdu:
+		if t.txn == nil {
+			return fmt.Errorf("%w: metadata has to be sent first", ErrInvalidTransactionStream)
+		}
+		if t.duplicate {
+			return nil
+		}
+		pdu, err := transaction.Pdu()
+		if err != nil {
+			return err
+		}
+		return t.handlePDU(ctx, pdu)
+	case types.Transaction_Which_edu:
+		if t.txn == nil {
+			return fmt.Errorf("%w: metadata has to be sent first", ErrInvalidTransactionStream)
+		}
+		if t.duplicate {
+			return nil
+		}
+		edu, err := transaction.Edu()
+		if err != nil {
+			return err
+		}
+		if t.server.transaction_handler == nil {
+			log.Println("Dropping EDU of transaction", t.txn.TxnID, "from", t.txn.Origin, "as there is no handler")
+			return nil
+		}
+		return t.server.transaction_handler.HandleEDU(ctx, *t.txn, edu)
+	default:
+		return fmt.Errorf("%w: unknown chunk %d", ErrInvalidTransactionStream, transaction.Which())
+	}
+}
+
+// handlePDU passes the PDU to the handler and reports the result to the sending server
+func (t *transactionStream) handlePDU(ctx context.Context, pdu types.Transaction_PDU) error {
+	index := uint32(len(t.pdu_results))
+
+	// A malformed or badly signed PDU only rejects itself instead of the whole transaction
+	pdu, info, err := t.server.checkPDU(ctx, pdu)
+	if err == nil {
+		if t.server.transaction_handler == nil {
+			log.Println("Dropping PDU of transaction", t.txn.TxnID, "from", t.txn.Origin, "as there is no handler")
+		} else {
+			err = t.server.transaction_handler.HandlePDU(ctx, *t.txn, pdu, info)
+		}
+	}
+
+	result := newPDUResult(index, info.EventID, err)
+	t.pdu_results = append(t.pdu_results, result)
+	if !t.results.IsValid() {
+		return nil
+	}
+	return writePDUResult(t.results_ctx, t.results, result)
+}
+
+// handleMetadata authenticates the sending server and starts the transaction.
+// Transactions that were completed before are answered with the previous results.
+func (t *transactionStream) handleMetadata(ctx context.Context, transaction types.Transaction) error {
+	auth_data, err := transaction.AuthData()
+	if err != nil {
+		return err
+	}
+	origin, err := t.server.authenticate(ctx, SendTransactions_MethodID, auth_data)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := transaction.Metadata()
+	if err != nil {
+		return err
+	}
+	txn_id, err := metadata.TxnID()
+	if err != nil {
+		return err
+	}
+	if txn_id == "" {
+		return fmt.Errorf("%w: missing txnID", ErrInvalidTransactionStream)
+	}
+	metadata_origin, err := metadata.Origin()
+	if err != nil {
+		return err
+	}
+	if metadata_origin != origin {
+		return fmt.Errorf("%w: origin %q does not match the authenticated origin %q", ErrUnauthenticated, metadata_origin, origin)
+	}
+
+	previous_results, completed, err := t.server.transactions.begin(origin, txn_id)
+	if err != nil {
+		return err
+	}
+	t.txn = &TransactionInfo{
+		TxnID:          txn_id,
+		Origin:         origin,
+		OriginServerTS: metadata.OriginServerTS(),
+	}
+	if !completed {
+		log.Println("Receiving transaction", txn_id, "from", origin)
+		return nil
+	}
+
+	log.Println("Transaction", txn_id, "from", origin, "was already processed, sending the previous results")
+	t.duplicate = true
+	t.pdu_results = previous_results
+	if !t.results.IsValid() {
+		return nil
+	}
+	for _, result := range previous_results {
+		err = writePDUResult(t.results_ctx, t.results, result)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *transactionStream) Done(ctx context.Context, call protocol.StreamCallback_done) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.finished {
+		return fmt.Errorf("%w: stream is already done", ErrInvalidTransactionStream)
+	}
+	defer t.finish()
+
+	if t.txn == nil {
+		return fmt.Errorf("%w: stream ended without metadata", ErrInvalidTransactionStream)
+	}
+	if !t.duplicate {
+		// The transaction is recorded before the handler commits it, so it is never processed twice. If the handler
+		// fails, the record is dropped again and the sending server can retry the transaction.
+		err := t.server.transactions.complete(t.txn.Origin, t.txn.TxnID, t.pdu_results)
+		if err != nil {
+			return err
+		}
+		if t.server.transaction_handler != nil {
+			err = t.server.transaction_handler.TransactionDone(ctx, *t.txn)
+			if err != nil {
+				return errors.Join(err, t.server.transactions.forget(t.txn.Origin, t.txn.TxnID))
+			}
+		}
+	}
+
+	if !t.results.IsValid() {
+		return nil
+	}
+	// Make sure all results arrived before telling the sending server we are done
+	err := t.results.WaitStreaming()
+	if err != nil {
+		return err
+	}
+	done, release := t.results.Done(t.results_ctx, nil)
+	defer release()
+	_, err = done.Struct()
+	return err
+}
+
+// Shutdown is called when the sending server releases the stream. This covers streams that never call done.
+func (t *transactionStream) Shutdown() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.finished {
+		t.finish()
+	}
+}
+
+// finish marks the stream as ended for draining and releases the results. The caller has to hold the lock.
+// A transaction that was not completed can be sent again afterwards.
+func (t *transactionStream) finish() {
+	t.finished = true
+	t.cancel_results()
+	if t.txn != nil && !t.duplicate {
+		t.server.transactions.abort(t.txn.Origin, t.txn.TxnID)
+	}
+	t.results.Release()
+	t.server.streams.end()
+}
@@ -0,0 +1,298 @@
+package rpcserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// ErrTransactionInProgress is returned if a transaction is sent again while it is still being processed.
+var ErrTransactionInProgress = errors.New("transaction is already being processed")
+
+// errTransactionLogClosed is returned when completing a transaction after the log was closed
+var errTransactionLogClosed = errors.New("transaction log is closed")
+
+// DefaultTransactionLogSize is how many completed transactions are remembered by default
+const DefaultTransactionLogSize = 10000
+
+type transactionKey struct {
+	origin string
+	txn_id string
+}
+
+// A completedTransaction is a line of the persisted transaction log
+type completedTransaction struct {
+	Origin  string      `json:"origin"`
+	TxnID   string      `json:"txn_id"`
+	Results []PDUResult `json:"results"`
+	// Set if an earlier line of the transaction is void, see transactionLog.forget
+	Forget bool `json:"forget,omitempty"`
+}
+
+// transactionLog remembers the PDU results of recently completed transactions so retries are not processed twice.
+//
+// If a path is set every completed transaction is appended to the file as one JSON line. The file is rewritten with
+// only the remembered transactions once it holds twice as many lines, so it does not grow forever.
+type transactionLog struct {
+	mu          sync.Mutex
+	max_entries int
+	path        string
+	// Nil if no path is set or the file could not be reopened after a rewrite, which is then retried
+	file   *os.File
+	closed bool
+	// Number of lines in file
+	lines     int
+	completed map[transactionKey][]PDUResult
+	// Keys of completed in the order they were added, used for eviction
+	order       []transactionKey
+	in_progress map[transactionKey]struct{}
+}
+
+// newTransactionLog creates a transactionLog. If path is not empty previously completed transactions are loaded from it.
+func newTransactionLog(maxEntries int, path string) (*transactionLog, error) {
+	if maxEntries <= 0 {
+		return nil, errors.New("transaction log size has to be positive")
+	}
+
+	txn_log := &transactionLog{
+		max_entries: maxEntries,
+		path:        path,
+		completed:   make(map[transactionKey][]PDUResult),
+		in_progress: make(map[transactionKey]struct{}),
+	}
+	if path == "" {
+		return txn_log, nil
+	}
+
+	err := txn_log.load()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	// Start with a compact file
+	err = txn_log.rewrite()
+	if err != nil {
+		return nil, err
+	}
+	return txn_log, nil
+}
+
+// begin marks the transaction as in progress. If it was completed before, its results are returned with true and
+// the transaction must not be processed again.
+func (l *transactionLog) begin(origin string, txnID string) ([]PDUResult, bool, error) {
+	key := transactionKey{origin, txnID}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// A transaction stays in progress until its stream ended, also after it was completed, as the completion is
+	// forgotten again if the handler fails to commit it
+	if _, ok := l.in_progress[key]; ok {
+		return nil, false, fmt.Errorf("%w: %s from %s", ErrTransactionInProgress, txnID, origin)
+	}
+	if results, ok := l.completed[key]; ok {
+		return results, true, nil
+	}
+	l.in_progress[key] = struct{}{}
+	return nil, false, nil
+}
+
+// abort ends a transaction started with begin. If it was not completed, it can be sent again.
+func (l *transactionLog) abort(origin string, txnID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.in_progress, transactionKey{origin, txnID})
+}
+
+// complete remembers the results of a transaction started with begin. If they can not be saved, the transaction is
+// not remembered either.
+func (l *transactionLog) complete(origin string, txnID string, results []PDUResult) error {
+	key := transactionKey{origin, txnID}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.add(key, results)
+	err := l.persist(completedTransaction{Origin: origin, TxnID: txnID, Results: results})
+	if err != nil {
+		l.remove(key)
+		return err
+	}
+	return nil
+}
+
+// forget drops a completed transaction whose processing failed afterwards, so it can be sent again
+func (l *transactionLog) forget(origin string, txnID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.remove(transactionKey{origin, txnID})
+	return l.persist(completedTransaction{Origin: origin, TxnID: txnID, Forget: true})
+}
+
+// close closes the log file. Transactions completed afterwards are no longer saved.
+func (l *transactionLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// persist writes a line to the log file. The caller has to hold the lock and has to have applied the line to the
+// remembered transactions already, as the file may be rewritten from them instead.
+func (l *transactionLog) persist(txn completedTransaction) error {
+	if l.path == "" {
+		return nil
+	}
+	if l.closed {
+		return errTransactionLogClosed
+	}
+	// A file that could not be reopened after the last rewrite is written anew
+	if l.file == nil || l.lines >= l.max_entries*2 {
+		return l.rewrite()
+	}
+	return l.append(txn)
+}
+
+// add remembers the results and evicts the oldest transactions if the log is full. The caller has to hold the lock.
+func (l *transactionLog) add(key transactionKey, results []PDUResult) {
+	if _, ok := l.completed[key]; !ok {
+		l.order = append(l.order, key)
+	}
+	l.completed[key] = results
+
+	for len(l.order) > l.max_entries {
+		delete(l.completed, l.order[0])
+		l.order = l.order[1:]
+	}
+}
+
+// remove forgets a completed transaction. The caller has to hold the lock.
+func (l *transactionLog) remove(key transactionKey) {
+	if _, ok := l.completed[key]; !ok {
+		return
+	}
+	delete(l.completed, key)
+	l.order = slices.DeleteFunc(l.order, func(k transactionKey) bool {
+		return k == key
+	})
+}
+
+// load reads the transactions from the log file
+func (l *transactionLog) load() error {
+	file, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<24)
+	line_number := 0
+	for scanner.Scan() {
+		line_number++
+		var txn completedTransaction
+		err := json.Unmarshal(scanner.Bytes(), &txn)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line_number, err)
+		}
+		if txn.Forget {
+			l.remove(transactionKey{txn.Origin, txn.TxnID})
+		} else {
+			l.add(transactionKey{txn.Origin, txn.TxnID}, txn.Results)
+		}
+	}
+	return scanner.Err()
+}
+
+// append writes a transaction to the end of the log file. The caller has to hold the lock.
+func (l *transactionLog) append(txn completedTransaction) error {
+	data, err := json.Marshal(txn)
+	if err != nil {
+		return err
+	}
+	_, err = l.file.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+	l.lines++
+	return nil
+}
+
+// rewrite replaces the log file with one only containing the remembered transactions. The caller has to hold the lock.
+func (l *transactionLog) rewrite() error {
+	// Write to a temporary file first so a crash never leaves a half written log behind
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	for _, key := range l.order {
+		data, err := json.Marshal(completedTransaction{Origin: key.origin, TxnID: key.txn_id, Results: l.completed[key]})
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		writer.Write(data)
+		writer.WriteByte('\n')
+	}
+	err = writer.Flush()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), l.path)
+	if err != nil {
+		return err
+	}
+
+	if l.file != nil {
+		l.file.Close()
+	}
+	l.lines = len(l.order)
+	// The transactions are saved at this point. If the file can not be opened for appending, the next write
+	// rewrites it again instead.
+	l.file, err = os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		l.file = nil
+		log.Println("Failed to reopen the transaction log, it is rewritten with the next transaction:", err)
+	}
+	return nil
+}
+
+// SetTransactionLog configures how many completed transactions are remembered to answer retries with the previous
+// results instead of processing them again. If path is not empty the transactions are kept in a log file at path
+// so they are remembered across restarts.
+//
+// By default DefaultTransactionLogSize transactions are kept in memory.
+func (s *RPCMatrixServer) SetTransactionLog(maxEntries int, path string) error {
+	txn_log, err := newTransactionLog(maxEntries, path)
+	if err != nil {
+		return err
+	}
+	if s.transactions != nil {
+		s.transactions.close()
+	}
+	s.transactions = txn_log
+	return nil
+}
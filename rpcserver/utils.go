@@ -2,8 +2,14 @@ package rpcserver
 
 import (
 	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	capnp "capnproto.org/go/capnp/v3"
+
 	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
 )
 
@@ -21,31 +27,200 @@ const (
 // prefix used.
 type KeyID string
 
-// This signs a capnproto byte sequeence in canonical form (has to be canonicalized by the caller) and adds the signature to the signatures list.
+// VerifyCapnprotoMap verifies the signatures of a map like VerifyCapnproto, so the order the entries were received in
+// does not matter.
+func VerifyCapnprotoMap(m types.Map, signatures types.Signature_List, serverNames []string, lookup KeyLookup) (VerificationResult, error) {
+	return VerifyCapnproto(capnp.Struct(m), types.Map_TypeID, signatures, serverNames, lookup)
+}
+
+// This signs the canonical form of a capnproto struct of the given type and adds the signature to the signatures list.
+// Maps in the struct are signed with their entries sorted by key, see CanonicalSorted.
 // This is the capnproto equivalent to https://spec.matrix.org/v1.9/appendices/#signing-json
 // This is not expected to yield the same signature as the json counterpart as the capnproto serialization is different.
-func SignCapnproto(signingName string, keyID KeyID, privateKey ed25519.PrivateKey, message []byte, signatures *types.Signature_List) error {
-	signature := ed25519.Sign(privateKey, message)
-
-	// Go doesnt support generic types from go-capnp yet https://github.com/capnproto/go-capnp/issues/27
-	list_size := signatures.Len()
-	map_entry := signatures.At(list_size - 1)
-	map_entry.SetServer(signingName)
-	signatures_map, err := map_entry.NewSignatures()
+//
+// Existing signatures of other servers and other keys are kept. As capnproto lists can not grow, signatures is replaced
+// by a new list in the same segment which the caller has to set on the parent struct.
+func SignCapnproto(signingName string, keyID KeyID, privateKey ed25519.PrivateKey, message capnp.Struct, typeID uint64, signatures *types.Signature_List) error {
+	builder, err := SignatureListBuilderFrom(*signatures)
 	if err != nil {
 		return err
 	}
-	signatures_map_wrapper := FromMap(&signatures_map, 1)
-	key, err := capnp.NewText(signatures_map_wrapper.Segment(), string(keyID))
+	err = builder.Sign(signingName, keyID, privateKey, message, typeID)
 	if err != nil {
 		return err
 	}
 
-	data, err := capnp.NewData(signatures_map_wrapper.Segment(), signature)
+	list, err := builder.Build(signatures.Segment())
 	if err != nil {
 		return err
 	}
-	signatures_map_wrapper.AddEntry(key.ToPtr(), data.ToPtr())
+	*signatures = list
+	return nil
+}
+
+// A KeyLookup returns the public key of the given server for the given key ID.
+// If the key is unknown it must return ErrKeyNotFound.
+type KeyLookup func(serverName string, keyID KeyID) (ed25519.PublicKey, error)
 
+// ErrKeyNotFound is returned by a KeyLookup if the requested key is unknown.
+var ErrKeyNotFound = errors.New("key not found")
+
+// SignatureStatus is the result of checking a single signature.
+type SignatureStatus int
+
+const (
+	// The signature is valid for the message
+	SignatureValid SignatureStatus = iota
+	// The signature does not match the message or is malformed
+	SignatureBad
+	// The key the signature was made with could not be found
+	SignatureUnknownKey
+)
+
+func (s SignatureStatus) String() string {
+	switch s {
+	case SignatureValid:
+		return "valid"
+	case SignatureBad:
+		return "bad"
+	case SignatureUnknownKey:
+		return "unknown key"
+	}
+	return "SignatureStatus(" + strconv.Itoa(int(s)) + ")"
+}
+
+// SignatureResult describes the outcome of checking one signature of a server.
+type SignatureResult struct {
+	ServerName string
+	KeyID      KeyID
+	Status     SignatureStatus
+	// Err is set if the key lookup failed for another reason than ErrKeyNotFound
+	Err error
+}
+
+// VerificationResult is the result of VerifyCapnproto.
+type VerificationResult struct {
+	// Every ed25519 signature of an expected server that was checked
+	Signatures []SignatureResult
+	// Expected servers that did not sign the message at all
+	MissingServers []string
+}
+
+// Valid returns true if every expected server has at least one valid signature
+func (r VerificationResult) Valid() bool {
+	return r.Err() == nil
+}
+
+// Err returns an error describing why the verification failed or nil if every expected server has a valid signature
+func (r VerificationResult) Err() error {
+	if len(r.MissingServers) > 0 {
+		return fmt.Errorf("missing signature from %s", strings.Join(r.MissingServers, ", "))
+	}
+
+	valid := make(map[string]bool)
+	for _, result := range r.Signatures {
+		if result.Status == SignatureValid {
+			valid[result.ServerName] = true
+		} else if _, ok := valid[result.ServerName]; !ok {
+			valid[result.ServerName] = false
+		}
+	}
+	for _, result := range r.Signatures {
+		if !valid[result.ServerName] {
+			if result.Err != nil {
+				return fmt.Errorf("unable to verify signature of %s with key %s: %w", result.ServerName, result.KeyID, result.Err)
+			}
+			return fmt.Errorf("no valid signature from %s: key %s is %s", result.ServerName, result.KeyID, result.Status)
+		}
+	}
 	return nil
 }
+
+// This verifies the signatures of the canonical form of a capnproto struct of the given type.
+// Maps in the struct are verified with their entries sorted by key, see CanonicalSorted, so the order they were
+// received in does not matter.
+// This is the capnproto equivalent to https://spec.matrix.org/v1.9/appendices/#checking-for-a-signature
+//
+// Every ed25519 signature of the given server names is checked against the keys returned by lookup.
+// Signatures of other servers and other algorithms are ignored.
+// An error is only returned if the message can not be canonicalized or the signature list can not be read.
+// Use VerificationResult.Err to check the outcome.
+func VerifyCapnproto(message capnp.Struct, typeID uint64, signatures types.Signature_List, serverNames []string, lookup KeyLookup) (VerificationResult, error) {
+	result := VerificationResult{}
+	canonical, err := CanonicalSorted(message, typeID)
+	if err != nil {
+		return result, err
+	}
+	signed := make(map[string]bool)
+
+	for i := 0; i < signatures.Len(); i++ {
+		signature := signatures.At(i)
+		server, err := signature.Server()
+		if err != nil {
+			return result, err
+		}
+		if !slices.Contains(serverNames, server) {
+			continue
+		}
+
+		signatures_map, err := signature.Signatures()
+		if err != nil {
+			return result, err
+		}
+		entries, err := signatures_map.Entries()
+		if err != nil {
+			return result, err
+		}
+
+		for j := 0; j < entries.Len(); j++ {
+			entry := entries.At(j)
+			key_id_ptr, err := entry.Key()
+			if err != nil {
+				return result, err
+			}
+			key_id := KeyID(key_id_ptr.Text())
+			if !strings.HasPrefix(string(key_id), "ed25519:") {
+				continue
+			}
+			signed[server] = true
+
+			signature_ptr, err := entry.Value()
+			if err != nil {
+				return result, err
+			}
+
+			result.Signatures = append(result.Signatures, checkSignature(server, key_id, canonical, signature_ptr.Data(), lookup))
+		}
+	}
+
+	for _, server := range serverNames {
+		if !signed[server] && !slices.Contains(result.MissingServers, server) {
+			result.MissingServers = append(result.MissingServers, server)
+		}
+	}
+
+	return result, nil
+}
+
+func checkSignature(server string, keyID KeyID, message []byte, signature []byte, lookup KeyLookup) SignatureResult {
+	result := SignatureResult{
+		ServerName: server,
+		KeyID:      keyID,
+	}
+
+	public_key, err := lookup(server, keyID)
+	if err != nil {
+		result.Status = SignatureUnknownKey
+		if !errors.Is(err, ErrKeyNotFound) {
+			result.Err = err
+		}
+		return result
+	}
+
+	if len(public_key) != ed25519.PublicKeySize || !ed25519.Verify(public_key, message, signature) {
+		result.Status = SignatureBad
+		return result
+	}
+	result.Status = SignatureValid
+	return result
+}
@@ -0,0 +1,39 @@
+package sender
+
+import (
+	"context"
+	"net"
+
+	"capnproto.org/go/capnp/v3/rpc"
+)
+
+// A Dialer opens a connection to the federation endpoint of a destination server.
+// The sender closes the connection once it is no longer needed or broken.
+type Dialer interface {
+	Dial(ctx context.Context, destination string) (*rpc.Conn, error)
+}
+
+// NetDialer connects to destinations via TCP
+type NetDialer struct {
+	// Resolve returns the address to connect to for a server name, e.g. "example.org:8449".
+	// If nil the server name is used as address.
+	Resolve func(ctx context.Context, destination string) (string, error)
+}
+
+func (d NetDialer) Dial(ctx context.Context, destination string) (*rpc.Conn, error) {
+	address := destination
+	if d.Resolve != nil {
+		var err error
+		address, err = d.Resolve(ctx, destination)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return rpc.NewConn(rpc.NewStreamTransport(conn), nil), nil
+}
@@ -0,0 +1,349 @@
+package sender
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// batchInfo describes the transaction that is currently sent to a destination.
+// It is kept until the transaction succeeded so retries contain the same events under the same transaction ID.
+type batchInfo struct {
+	TxnID string `json:"txn_id"`
+	// Number of PDUs and EDUs from the front of the queue that are part of the transaction
+	PDUs int `json:"pdus"`
+	EDUs int `json:"edus"`
+}
+
+// A batch is a transaction ready to be sent
+type batch struct {
+	TxnID string
+	// Serialized capnproto messages with a Transaction.PDU or Transaction.EDU as root
+	PDUs [][]byte
+	EDUs [][]byte
+}
+
+// queueState is the persisted part of a destinationQueue. It is the first line of the queue file.
+type queueState struct {
+	Destination string `json:"destination"`
+	// The transaction ID used for the next transaction
+	NextTxnID int64      `json:"next_txn_id"`
+	InFlight  *batchInfo `json:"in_flight,omitempty"`
+	PDUs      [][]byte   `json:"pdus"`
+	EDUs      [][]byte   `json:"edus"`
+}
+
+// A queueRecord is a change appended to the queue file after the queueState
+type queueRecord struct {
+	PDU []byte `json:"pdu,omitempty"`
+	EDU []byte `json:"edu,omitempty"`
+	// Set when a transaction was started, together with the transaction ID used for the next one
+	InFlight  *batchInfo `json:"in_flight,omitempty"`
+	NextTxnID int64      `json:"next_txn_id,omitempty"`
+}
+
+// apply adds the change of a record to the state
+func (s *queueState) apply(record queueRecord) {
+	if record.PDU != nil {
+		s.PDUs = append(s.PDUs, record.PDU)
+	}
+	if record.EDU != nil {
+		s.EDUs = append(s.EDUs, record.EDU)
+	}
+	if record.InFlight != nil {
+		s.InFlight = record.InFlight
+		s.NextTxnID = record.NextTxnID
+	}
+}
+
+// destinationQueue holds the events for a single destination
+//
+// If the queue is persisted, its file starts with a line holding the queueState followed by one queueRecord per
+// line for every event added and transaction started since. Once a transaction was sent the file is compacted to
+// only the queueState, so adding an event only has to append a line.
+type destinationQueue struct {
+	destination string
+	// File the queue is persisted in. Empty if the queue is only kept in memory.
+	path string
+	// Whether the file has been written with the state yet, otherwise records can not be appended
+	compacted bool
+	// Signalled when events were added
+	wake chan struct{}
+	// Signalled when the backoff should be skipped
+	retry chan struct{}
+
+	mu       sync.Mutex
+	state    queueState
+	failures int
+	retry_at time.Time
+}
+
+func newDestinationQueue(destination string, dir string) *destinationQueue {
+	return newQueue(queueState{
+		Destination: destination,
+		// Transaction IDs only have to be unique per origin. Starting at the current time keeps them unique even if
+		// the queue was not persisted.
+		NextTxnID: time.Now().UnixMilli(),
+	}, dir)
+}
+
+func newQueue(state queueState, dir string) *destinationQueue {
+	queue := &destinationQueue{
+		destination: state.Destination,
+		wake:        make(chan struct{}, 1),
+		retry:       make(chan struct{}, 1),
+		state:       state,
+	}
+	if dir != "" {
+		queue.path = filepath.Join(dir, url.PathEscape(state.Destination)+".json")
+	}
+	return queue
+}
+
+// loadQueues reads the queues persisted in dir
+func loadQueues(dir string) ([]*destinationQueue, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var queues []*destinationQueue
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		state, err := readQueue(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if state.Destination == "" {
+			return nil, fmt.Errorf("%s: queue without destination", path)
+		}
+		queue := newQueue(state, dir)
+		// Start with a compact file
+		err = queue.compact()
+		if err != nil {
+			return nil, err
+		}
+		queues = append(queues, queue)
+	}
+	return queues, nil
+}
+
+// readQueue reads the state of a queue file and applies the records appended to it
+func readQueue(path string) (queueState, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return queueState{}, err
+	}
+	defer file.Close()
+
+	var state queueState
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<26)
+	line_number := 0
+	for scanner.Scan() {
+		line_number++
+		if line_number == 1 {
+			err = json.Unmarshal(scanner.Bytes(), &state)
+			if err != nil {
+				return queueState{}, fmt.Errorf("line %d: %w", line_number, err)
+			}
+			continue
+		}
+		var record queueRecord
+		err = json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return queueState{}, fmt.Errorf("line %d: %w", line_number, err)
+		}
+		state.apply(record)
+	}
+	return state, scanner.Err()
+}
+
+func (q *destinationQueue) addPDU(data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	record := queueRecord{PDU: data}
+	q.state.apply(record)
+	return q.append(record)
+}
+
+func (q *destinationQueue) addEDU(data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	record := queueRecord{EDU: data}
+	q.state.apply(record)
+	return q.append(record)
+}
+
+// notify wakes up the worker of the queue
+func (q *destinationQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// resetBackoff makes the worker retry right away
+func (q *destinationQueue) resetBackoff() {
+	q.mu.Lock()
+	q.failures = 0
+	q.retry_at = time.Time{}
+	q.mu.Unlock()
+
+	select {
+	case q.retry <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the transaction to send. This is the one in flight if the last attempt failed.
+// It returns false if there is nothing to send.
+func (q *destinationQueue) next() (batch, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.state.InFlight == nil {
+		if len(q.state.PDUs) == 0 && len(q.state.EDUs) == 0 {
+			return batch{}, false
+		}
+		record := queueRecord{
+			InFlight: &batchInfo{
+				TxnID: strconv.FormatInt(q.state.NextTxnID, 10),
+				PDUs:  min(len(q.state.PDUs), MaxPDUsPerTransaction),
+				EDUs:  min(len(q.state.EDUs), MaxEDUsPerTransaction),
+			},
+			NextTxnID: q.state.NextTxnID + 1,
+		}
+		q.state.apply(record)
+		// Persist the batch so a restart retries the same events under the same transaction ID
+		err := q.append(record)
+		if err != nil {
+			log.Println("Failed to persist queue of", q.destination, ":", err)
+		}
+	}
+
+	in_flight := q.state.InFlight
+	return batch{
+		TxnID: in_flight.TxnID,
+		PDUs:  slices.Clone(q.state.PDUs[:in_flight.PDUs]),
+		EDUs:  slices.Clone(q.state.EDUs[:in_flight.EDUs]),
+	}, true
+}
+
+// sent removes the events of a successful transaction from the queue
+func (q *destinationQueue) sent(sent batch) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	in_flight := q.state.InFlight
+	if in_flight == nil || in_flight.TxnID != sent.TxnID {
+		return errors.New("transaction is not in flight")
+	}
+	q.state.PDUs = slices.Delete(q.state.PDUs, 0, in_flight.PDUs)
+	q.state.EDUs = slices.Delete(q.state.EDUs, 0, in_flight.EDUs)
+	q.state.InFlight = nil
+	q.failures = 0
+	q.retry_at = time.Time{}
+	return q.compact()
+}
+
+// failed records a failed attempt and returns how long to wait before the next one
+func (q *destinationQueue) failed() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.failures++
+	delay := MaxBackoff
+	// Avoid overflowing the shift for destinations that are down for a long time
+	if q.failures < 32 {
+		delay = min(MinBackoff<<(q.failures-1), MaxBackoff)
+	}
+	q.retry_at = time.Now().Add(delay)
+	return delay
+}
+
+func (q *destinationQueue) metrics() QueueMetrics {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueMetrics{
+		PendingPDUs: len(q.state.PDUs),
+		PendingEDUs: len(q.state.EDUs),
+		Failures:    q.failures,
+		RetryAt:     q.retry_at,
+	}
+}
+
+// append writes a record to the end of the queue file. The caller has to hold the lock.
+//
+// The file is only opened for the write, as there may be more queues than we can keep files open.
+func (q *destinationQueue) append(record queueRecord) error {
+	if q.path == "" {
+		return nil
+	}
+	if !q.compacted {
+		return q.compact()
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// compact replaces the queue file with one only containing the state. The caller has to hold the lock.
+func (q *destinationQueue) compact() error {
+	if q.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(q.state)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a half written queue behind
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(append(data, '\n'))
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), q.path)
+	if err != nil {
+		return err
+	}
+	q.compacted = true
+	return nil
+}
@@ -0,0 +1,242 @@
+// Package sender pushes PDUs and EDUs to other servers.
+//
+// Every destination server has its own queue which is sent in transactions of up to MaxPDUsPerTransaction PDUs and
+// MaxEDUsPerTransaction EDUs via MatrixFederation.sendTransactions. Failed transactions are retried with the same
+// transaction ID and an exponential backoff. The queues are optionally persisted to a directory so a restart does not
+// lose any events.
+package sender
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	capnp "capnproto.org/go/capnp/v3"
+
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+	"github.com/MTRNord/matrix_protobuf_fed/rpcserver"
+)
+
+// Limits of a single transaction as specified in https://spec.matrix.org/v1.9/server-server-api/#transactions
+const (
+	MaxPDUsPerTransaction = 50
+	MaxEDUsPerTransaction = 100
+)
+
+// Backoff of failed transactions
+const (
+	MinBackoff = time.Second * 5
+	MaxBackoff = time.Hour
+)
+
+// How long we wait for a transaction to be processed by the destination
+const transactionTimeout = time.Minute * 2
+
+// ErrSenderClosed is returned when queueing events after Close was called.
+var ErrSenderClosed = errors.New("sender: closed")
+
+// QueueMetrics describes the state of the queue of a destination
+type QueueMetrics struct {
+	PendingPDUs int
+	PendingEDUs int
+	// Number of failed attempts since the last successful transaction
+	Failures int
+	// When the next attempt is made. Zero if the queue is not backing off.
+	RetryAt time.Time
+}
+
+// Sender sends events to other servers.
+type Sender struct {
+	keys   *rpcserver.KeyRing
+	dialer Dialer
+	// Directory the queues are persisted in. Empty if the queues are only kept in memory.
+	dir string
+
+	mu     sync.Mutex
+	queues map[string]*destinationQueue
+	closed bool
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New creates a Sender which signs the transactions with the active key of keys.
+//
+// If dir is not empty the queues are persisted in it and the queues of a previous run are loaded and resumed.
+func New(keys *rpcserver.KeyRing, dialer Dialer, dir string) (*Sender, error) {
+	s := &Sender{
+		keys:   keys,
+		dialer: dialer,
+		dir:    dir,
+		queues: make(map[string]*destinationQueue),
+		stop:   make(chan struct{}),
+	}
+	if dir == "" {
+		return s, nil
+	}
+
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+	queues, err := loadQueues(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, queue := range queues {
+		s.start(queue)
+	}
+	return s, nil
+}
+
+// SendPDU queues a PDU for the destination. The PDU is copied so the caller may release its message afterwards.
+func (s *Sender) SendPDU(destination string, pdu types.Transaction_PDU) error {
+	data, err := copyStruct(capnp.Struct(pdu))
+	if err != nil {
+		return err
+	}
+	return s.enqueue(destination, func(q *destinationQueue) error {
+		return q.addPDU(data)
+	})
+}
+
+// SendEDU queues an EDU for the destination. The EDU is copied so the caller may release its message afterwards.
+func (s *Sender) SendEDU(destination string, edu types.Transaction_EDU) error {
+	data, err := copyStruct(capnp.Struct(edu))
+	if err != nil {
+		return err
+	}
+	return s.enqueue(destination, func(q *destinationQueue) error {
+		return q.addEDU(data)
+	})
+}
+
+// Wake retries the queue of the destination right away instead of waiting for the backoff to expire.
+// This is useful if we learn that the destination is reachable again, e.g. because it sent us a transaction.
+func (s *Sender) Wake(destination string) {
+	s.mu.Lock()
+	queue, ok := s.queues[destination]
+	s.mu.Unlock()
+	if ok {
+		queue.resetBackoff()
+	}
+}
+
+// Metrics returns the state of the queues of all destinations we have events for
+func (s *Sender) Metrics() map[string]QueueMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metrics := make(map[string]QueueMetrics, len(s.queues))
+	for destination, queue := range s.queues {
+		metrics[destination] = queue.metrics()
+	}
+	return metrics
+}
+
+// Close stops sending. Transactions that are in flight are aborted and retried after the next start.
+func (s *Sender) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.stop)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Sender) enqueue(destination string, add func(q *destinationQueue) error) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrSenderClosed
+	}
+	queue, ok := s.queues[destination]
+	if !ok {
+		queue = newDestinationQueue(destination, s.dir)
+		s.start(queue)
+	}
+	s.mu.Unlock()
+
+	err := add(queue)
+	if err != nil {
+		return err
+	}
+	queue.notify()
+	return nil
+}
+
+// start registers the queue and runs its worker. The caller has to hold the lock unless the Sender is being created.
+func (s *Sender) start(queue *destinationQueue) {
+	s.queues[queue.destination] = queue
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(queue)
+	}()
+}
+
+// run sends the queue of a destination until the Sender is closed
+func (s *Sender) run(queue *destinationQueue) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+
+	conn := &connection{dialer: s.dialer, destination: queue.destination}
+	defer conn.close()
+
+	for {
+		batch, ok := queue.next()
+		if !ok {
+			select {
+			case <-queue.wake:
+				continue
+			case <-s.stop:
+				return
+			}
+		}
+
+		err := s.sendTransaction(ctx, conn, batch)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			conn.close()
+			delay := queue.failed()
+			log.Println("Failed to send transaction", batch.TxnID, "to", queue.destination, "retrying in", delay, ":", err)
+			select {
+			case <-time.After(delay):
+			case <-queue.retry:
+			case <-s.stop:
+				return
+			}
+			continue
+		}
+
+		err = queue.sent(batch)
+		if err != nil {
+			log.Println("Failed to persist queue of", queue.destination, ":", err)
+		}
+	}
+}
+
+// copyStruct serializes the struct into a message of its own
+func copyStruct(s capnp.Struct) ([]byte, error) {
+	msg, _, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		return nil, err
+	}
+	err = msg.SetRoot(s.ToPtr())
+	if err != nil {
+		return nil, err
+	}
+	return msg.Marshal()
+}
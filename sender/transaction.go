@@ -0,0 +1,168 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	capnp "capnproto.org/go/capnp/v3"
+	"capnproto.org/go/capnp/v3/flowcontrol"
+	"capnproto.org/go/capnp/v3/rpc"
+
+	protocol "github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1"
+	"github.com/MTRNord/matrix_protobuf_fed/proto/federation/v1/types"
+	"github.com/MTRNord/matrix_protobuf_fed/rpcserver"
+)
+
+// connection is the connection to a destination. It is opened on first use and kept until it breaks.
+type connection struct {
+	dialer      Dialer
+	destination string
+	conn        *rpc.Conn
+	client      protocol.MatrixFederation
+}
+
+func (c *connection) get(ctx context.Context) (protocol.MatrixFederation, error) {
+	if c.conn != nil {
+		return c.client, nil
+	}
+
+	conn, err := c.dialer.Dial(ctx, c.destination)
+	if err != nil {
+		return protocol.MatrixFederation{}, err
+	}
+	c.conn = conn
+	c.client = protocol.MatrixFederation(conn.Bootstrap(ctx))
+	return c.client, nil
+}
+
+func (c *connection) close() {
+	if c.conn == nil {
+		return
+	}
+	c.client.Release()
+	c.conn.Close()
+	c.conn = nil
+}
+
+// sendTransaction sends one transaction and waits until the destination processed all its PDUs.
+// Rejected PDUs are logged but do not fail the transaction, only errors of the transaction as a whole do.
+func (s *Sender) sendTransaction(ctx context.Context, conn *connection, batch batch) error {
+	ctx, cancel := context.WithTimeout(ctx, transactionTimeout)
+	defer cancel()
+
+	client, err := conn.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	results := rpcserver.NewTransactionResults(nil)
+	results_client := protocol.StreamCallback_ServerToClient(results)
+	future, release := client.SendTransactions(ctx, func(p protocol.MatrixFederation_sendTransactions_Params) error {
+		return p.SetResults(results_client.AddRef())
+	})
+	defer release()
+	results_client.Release()
+
+	callback := future.Callback()
+	callback.SetFlowLimiter(flowcontrol.NewFixedLimiter(1 << 17))
+
+	signing_key := s.keys.SigningKey()
+	err = callback.Write(ctx, func(p protocol.StreamCallback_write_Params) error {
+		transaction, err := types.NewTransaction(p.Segment())
+		if err != nil {
+			return err
+		}
+		auth_data, err := rpcserver.NewAuthData(p.Segment(), rpcserver.SendTransactions_MethodID, conn.destination, signing_key)
+		if err != nil {
+			return err
+		}
+		err = transaction.SetAuthData(auth_data)
+		if err != nil {
+			return err
+		}
+
+		metadata, err := transaction.NewMetadata()
+		if err != nil {
+			return err
+		}
+		err = metadata.SetTxnID(batch.TxnID)
+		if err != nil {
+			return err
+		}
+		err = metadata.SetOrigin(signing_key.ServerName())
+		if err != nil {
+			return err
+		}
+		metadata.SetOriginServerTS(time.Now().UnixMilli())
+		return p.SetValue(transaction.ToPtr())
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, pdu := range batch.PDUs {
+		err = writeItem(ctx, callback, pdu, func(transaction types.Transaction, root capnp.Ptr) error {
+			return transaction.SetPdu(types.Transaction_PDU(root.Struct()))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, edu := range batch.EDUs {
+		err = writeItem(ctx, callback, edu, func(transaction types.Transaction, root capnp.Ptr) error {
+			return transaction.SetEdu(types.Transaction_EDU(root.Struct()))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err = callback.WaitStreaming()
+	if err != nil {
+		return err
+	}
+	done, release_done := callback.Done(ctx, nil)
+	_, err = done.Struct()
+	release_done()
+	if err != nil {
+		return err
+	}
+
+	pdu_results, err := results.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pdu_results) != len(batch.PDUs) {
+		return fmt.Errorf("got %d PDU results for %d PDUs", len(pdu_results), len(batch.PDUs))
+	}
+	for _, result := range results.Rejected() {
+		log.Println("PDU", result.Index, result.EventID, "of transaction", batch.TxnID, "was rejected by", conn.destination, ":", result.Error)
+	}
+	return nil
+}
+
+// writeItem writes a serialized PDU or EDU to the transaction stream
+func writeItem(ctx context.Context, callback protocol.StreamCallback, data []byte, set func(types.Transaction, capnp.Ptr) error) error {
+	msg, err := capnp.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	root, err := msg.Root()
+	if err != nil {
+		return err
+	}
+
+	return callback.Write(ctx, func(p protocol.StreamCallback_write_Params) error {
+		transaction, err := types.NewTransaction(p.Segment())
+		if err != nil {
+			return err
+		}
+		err = set(transaction, root)
+		if err != nil {
+			return err
+		}
+		return p.SetValue(transaction.ToPtr())
+	})
+}